@@ -26,7 +26,10 @@ func main() {
 	}
 
 	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model)
+	ag, err := agent.NewAgent(task, model)
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run()
 
 	if err != nil {
@@ -26,10 +26,13 @@ func main() {
 		log.Fatal(err)
 	}
 	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model, agent.WithBrowserConfig(browser.BrowserConfig{
+	ag, err := agent.NewAgent(task, model, agent.WithBrowserConfig(browser.BrowserConfig{
 		"headless":            false,
 		"browser_binary_path": "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
 	}))
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run()
 
 	if err != nil {
@@ -27,7 +27,7 @@ func main() {
 	}
 
 	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model, agent.WithInitialActions([]map[string]interface{}{
+	ag, err := agent.NewAgent(task, model, agent.WithInitialActions([]map[string]interface{}{
 		{
 			"go_to_url": map[string]interface{}{
 				"url": "https://google.com",
@@ -36,6 +36,9 @@ func main() {
 	}), agent.WithBrowserConfig(browser.BrowserConfig{
 		"cookies_file": "cookies.json",
 	}))
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run()
 
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/nerdface-ai/browser-use-go/internals/controller"
 	"github.com/nerdface-ai/browser-use-go/pkg/agent"
 	"github.com/nerdface-ai/browser-use-go/pkg/dotenv"
 )
@@ -26,13 +27,12 @@ func main() {
 	}
 
 	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model, agent.WithInitialActions([]map[string]interface{}{
-		{
-			"go_to_url": map[string]interface{}{
-				"url": "https://www.google.com",
-			},
-		},
+	ag, err := agent.NewAgent(task, model, agent.WithInitialActions([]map[string]interface{}{
+		controller.GoToURL("https://www.google.com"),
 	}))
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run()
 
 	if err != nil {
@@ -34,9 +34,12 @@ func main() {
 	}
 
 	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model, agent.WithAgentSettings(agent.AgentSettingsConfig{
+	ag, err := agent.NewAgent(task, model, agent.WithAgentSettings(agent.AgentSettingsConfig{
 		"planner_llm": plannerModel,
 	}))
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run()
 
 	if err != nil {
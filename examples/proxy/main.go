@@ -3,20 +3,20 @@ package main
 import (
 	"context"
 	"os"
+	"sync"
 
 	"github.com/charmbracelet/log"
 	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/nerdface-ai/browser-use-go/pkg/agent"
 	"github.com/nerdface-ai/browser-use-go/pkg/browser"
 	"github.com/nerdface-ai/browser-use-go/pkg/dotenv"
+	"github.com/playwright-community/playwright-go"
 )
 
 func main() {
 	log.SetLevel(log.DebugLevel)
 	dotenv.LoadEnv(".env")
 
-	log.Debug(os.Getenv("OPENAI_API_KEY"))
-
 	ctx := context.Background()
 	model, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
 		Model:  "gpt-4.1-mini",
@@ -26,18 +26,64 @@ func main() {
 		log.Fatal(err)
 	}
 
-	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model, agent.WithBrowserConfig(browser.BrowserConfig{
+	// One browser for all agents. "proxy" is the default egress of the browser, also passed as
+	// --proxy-server to a browser_binary_path chrome. Each new context gets a proxy of "proxy_pool".
+	b := browser.NewBrowser(browser.BrowserConfig{
 		"proxy": map[string]interface{}{
-			"server":   "your proxy server address and port",
-			"username": "your proxy user name",
-			"password": "your proxy password",
+			"server": "your default proxy server address and port",
+		},
+		"proxy_pool": []interface{}{
+			map[string]interface{}{
+				"server":   "your first proxy server address and port",
+				"username": "your proxy user name",
+				"password": "your proxy password",
+			},
+			map[string]interface{}{
+				"server":   "your second proxy server address and port",
+				"username": "your proxy user name",
+				"password": "your proxy password",
+			},
 		},
-	}))
-	historyResult, err := ag.Run()
+	})
+	defer b.Close()
+	// start the shared browser once, before the agents use it
+	if err := b.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
 
-	if err != nil {
-		log.Fatal(err.Error())
+	// Proxies are handed out round robin by default. A selector can pick them by context instead,
+	// here by the locale of the context.
+	b.SetProxySelector(func(bc *browser.BrowserContext, pool []playwright.Proxy) (*playwright.Proxy, error) {
+		if bc.Config.Locale == "de-DE" {
+			return &pool[1], nil
+		}
+		return &pool[0], nil
+	})
+
+	tasks := map[string]string{
+		"en-US": "go to google.com and find out which country the search results are localized for",
+		"de-DE": "go to google.de and find out which country the search results are localized for",
+	}
+	var wg sync.WaitGroup
+	for locale, task := range tasks {
+		wg.Add(1)
+		go func(locale string, task string) {
+			defer wg.Done()
+			// a context can also use its own proxy with browser.WithContextProxy
+			bc := b.NewContext(browser.WithLocale(locale))
+			defer bc.Close()
+			ag, err := agent.NewAgent(task, model, agent.WithBrowser(b), agent.WithBrowserContext(bc))
+			if err != nil {
+				log.Error(err)
+				return
+			}
+			historyResult, err := ag.Run()
+			if err != nil {
+				log.Error(err)
+				return
+			}
+			log.Infof("agent output (%s via %s): %s", locale, bc.ProxyServer(), *historyResult.LastResult().ExtractedContent)
+		}(locale, task)
 	}
-	log.Infof("agent output: %s", *historyResult.LastResult().ExtractedContent)
+	wg.Wait()
 }
@@ -26,10 +26,13 @@ func main() {
 	}
 
 	task := "go to x.com login page and insert x_name and x_password."
-	ag := agent.NewAgent(task, model, agent.WithSensitiveData(map[string]string{
+	ag, err := agent.NewAgent(task, model, agent.WithSensitiveData(map[string]string{
 		"x_name":     "currybab_",
 		"x_password": "testtest",
 	}))
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run()
 
 	if err != nil {
@@ -31,7 +31,10 @@ func TestAgentRun(t *testing.T) {
 	}
 
 	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model)
+	ag, err := agent.NewAgent(task, model)
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run(agent.WithMaxSteps(20))
 
 	if err != nil {
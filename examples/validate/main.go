@@ -43,7 +43,10 @@ func main() {
 	}
 
 	task := "do google search and find who is Elon Musk's wife"
-	ag := agent.NewAgent(task, model, agent.WithValidateLLM(validateModel))
+	ag, err := agent.NewAgent(task, model, agent.WithValidateLLM(validateModel))
+	if err != nil {
+		log.Fatal(err)
+	}
 	historyResult, err := ag.Run()
 
 	if err != nil {
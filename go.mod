@@ -12,6 +12,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/stretchr/testify v1.10.0
 	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/sys v0.32.0
 )
 
 require (
@@ -95,7 +96,6 @@ require (
 	github.com/playwright-community/playwright-go v0.5101.0
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/exp v0.0.0-20231006140011-7918f672742d // indirect
-	golang.org/x/sys v0.32.0 // indirect
 )
 
 replace github.com/invopop/jsonschema => github.com/currybab/jsonschema v0.0.0-20250429080118-779849695a6f
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+)
+
+// Typed builders for the default actions, e.g. to be used as initial actions of an agent:
+//
+//	agent.WithInitialActions([]map[string]interface{}{
+//		controller.GoToURL("https://www.google.com"),
+//		controller.Click(5),
+//	})
+
+// Build an ActModel from the action name and its params model
+func newActModel(name string, params interface{}) ActModel {
+	paramMap := map[string]interface{}{}
+	b, err := json.Marshal(params)
+	if err == nil {
+		json.Unmarshal(b, &paramMap)
+	}
+	return ActModel{name: paramMap}
+}
+
+func Done(text string, success bool) ActModel {
+	return newActModel("done", DoneAction{Text: text, Success: success})
+}
+
+func Click(index int) ActModel {
+	return newActModel("click_element_by_index", ClickElementAction{Index: index})
+}
+
+func InputText(index int, text string) ActModel {
+	return newActModel("input_text", InputTextAction{Index: index, Text: text})
+}
+
+// Upload one or more of the available files to the file input at or near the element
+func UploadFile(index int, paths ...string) ActModel {
+	return newActModel("upload_file", UploadFileAction{Index: index, Paths: paths})
+}
+
+func SearchGoogle(query string) ActModel {
+	return newActModel("search_google", SearchGoogleAction{Query: query})
+}
+
+func GoToURL(url string) ActModel {
+	return newActModel("go_to_url", GoToUrlAction{Url: url})
+}
+
+func GoBack() ActModel {
+	return newActModel("go_back", GoBackAction{})
+}
+
+func Wait(seconds int) ActModel {
+	return newActModel("wait", WaitAction{Seconds: seconds})
+}
+
+func SavePdf() ActModel {
+	return newActModel("save_pdf", SavePdfAction{})
+}
+
+func SwitchTab(pageId int) ActModel {
+	return newActModel("switch_tab", SwitchTabAction{PageId: pageId})
+}
+
+func OpenTab(url string) ActModel {
+	return newActModel("open_tab", OpenTabAction{Url: url})
+}
+
+func CloseTab(pageId int) ActModel {
+	return newActModel("close_tab", CloseTabAction{PageId: pageId})
+}
+
+func ExtractContent(goal string, shouldStripLinkUrls bool) ActModel {
+	return newActModel("extract_content", ExtractContentAction{Goal: goal, ShouldStripLinkUrls: shouldStripLinkUrls})
+}
+
+// Scroll down by the given amount of pixels, or one page if amount is nil
+func ScrollDown(amount *int) ActModel {
+	return newActModel("scroll_down", ScrollDownAction{Amount: amount})
+}
+
+// Scroll up by the given amount of pixels, or one page if amount is nil
+func ScrollUp(amount *int) ActModel {
+	return newActModel("scroll_up", ScrollUpAction{Amount: amount})
+}
+
+func SendKeys(keys string) ActModel {
+	return newActModel("send_keys", SendKeysAction{Keys: keys})
+}
+
+func ScrollToText(text string) ActModel {
+	return newActModel("scroll_to_text", ScrollToTextAction{Text: text})
+}
+
+func GetDropdownOptions(index int) ActModel {
+	return newActModel("get_dropdown_options", GetDropdownOptionsAction{Index: index})
+}
+
+func SelectDropdownOption(index int, text string) ActModel {
+	return newActModel("select_dropdown_option", SelectDropdownOptionAction{Index: index, Text: text})
+}
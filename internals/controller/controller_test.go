@@ -3,6 +3,9 @@ package controller_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -46,8 +49,8 @@ func tempFunction(_ context.Context, arg1 controller.DoneAction) (*controller.Ac
 func TestNewController(t *testing.T) {
 	c := controller.NewController()
 	t.Log(c)
-	if len(c.Registry.Registry.Actions) != 19 {
-		t.Error("expected 19 actions, got", len(c.Registry.Registry.Actions))
+	if len(c.Registry.Registry.Actions) != 20 {
+		t.Error("expected 20 actions, got", len(c.Registry.Registry.Actions))
 	}
 }
 
@@ -717,3 +720,191 @@ func TestSendKeys(t *testing.T) {
 func TestDragDrop(t *testing.T) {
 
 }
+
+func TestValidateAction(t *testing.T) {
+	c := controller.NewController()
+	actionModel := c.Registry.CreateActionModel(nil, nil)
+
+	valid := &controller.ActModel{"click_element_by_index": map[string]interface{}{"index": 5}}
+	assert.NoError(t, actionModel.ValidateAction(valid))
+
+	noParams := &controller.ActModel{"go_back": nil}
+	assert.NoError(t, actionModel.ValidateAction(noParams))
+
+	unknown := &controller.ActModel{"fly_to_the_moon": map[string]interface{}{}}
+	err := actionModel.ValidateAction(unknown)
+	assert.ErrorContains(t, err, "not available")
+
+	wrongType := &controller.ActModel{"click_element_by_index": map[string]interface{}{"index": "five"}}
+	err = actionModel.ValidateAction(wrongType)
+	assert.ErrorContains(t, err, "invalid params for \"click_element_by_index\"")
+
+	missingField := &controller.ActModel{"go_to_url": map[string]interface{}{}}
+	err = actionModel.ValidateAction(missingField)
+	assert.ErrorContains(t, err, "url")
+
+	twoActions := &controller.ActModel{"go_back": nil, "wait": map[string]interface{}{"seconds": 1}}
+	err = actionModel.ValidateAction(twoActions)
+	assert.ErrorContains(t, err, "exactly one action")
+
+	notObject := &controller.ActModel{"go_to_url": "https://example.com"}
+	err = actionModel.ValidateAction(notObject)
+	assert.ErrorContains(t, err, "params must be an object")
+}
+
+func TestActionBuilders(t *testing.T) {
+	c := controller.NewController()
+	actions := []controller.ActModel{
+		controller.Done("finished", true),
+		controller.Click(5),
+		controller.InputText(2, "hello"),
+		controller.UploadFile(4, "/tmp/a.txt", "/tmp/b.txt"),
+		controller.SearchGoogle("browser-use"),
+		controller.GoToURL("https://example.com"),
+		controller.GoBack(),
+		controller.Wait(1),
+		controller.SavePdf(),
+		controller.SwitchTab(0),
+		controller.OpenTab("https://example.com"),
+		controller.CloseTab(1),
+		controller.ExtractContent("names", false),
+		controller.ScrollDown(nil),
+		controller.ScrollUp(playwright.Int(200)),
+		controller.SendKeys("Enter"),
+		controller.ScrollToText("footer"),
+		controller.GetDropdownOptions(3),
+		controller.SelectDropdownOption(3, "Dog"),
+	}
+	for _, action := range actions {
+		assert.NoError(t, c.Registry.ValidateAction(&action))
+	}
+	click := controller.Click(5)
+	if assert.NotNil(t, click.GetIndex()) {
+		assert.Equal(t, 5, *click.GetIndex())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	c := controller.NewController()
+	order := []string{}
+	c.Use(func(next controller.ActionHandler) controller.ActionHandler {
+		return func(call *controller.ActionCall) (*controller.ActionResult, error) {
+			order = append(order, "outer:"+call.Name)
+			result, err := next(call)
+			if err == nil && result.ExtractedContent != nil {
+				result.ExtractedContent = playwright.String(*result.ExtractedContent + " (checked)")
+			}
+			return result, err
+		}
+	}, func(next controller.ActionHandler) controller.ActionHandler {
+		return func(call *controller.ActionCall) (*controller.ActionResult, error) {
+			order = append(order, "inner:"+call.Name)
+			if call.Name == "go_to_url" {
+				return nil, errors.New("navigation is not allowed")
+			}
+			call.Params["text"] = strings.ToUpper(call.Params["text"].(string))
+			return next(call)
+		}
+	})
+
+	done := controller.Done("finished", true)
+	result, err := c.ExecuteAction(&done, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "FINISHED (checked)", *result.ExtractedContent)
+	assert.Equal(t, "finished", done["done"].(map[string]interface{})["text"], "the action of the model must not be changed")
+
+	goToUrl := controller.GoToURL("https://example.com")
+	_, err = c.ExecuteAction(&goToUrl, nil, nil, nil, nil)
+	assert.EqualError(t, err, "navigation is not allowed")
+
+	assert.Equal(t, []string{"outer:done", "inner:done", "outer:go_to_url", "inner:go_to_url"}, order)
+}
+
+func TestMiddlewareNestedParams(t *testing.T) {
+	c := controller.NewController()
+	c.Use(func(next controller.ActionHandler) controller.ActionHandler {
+		return func(call *controller.ActionCall) (*controller.ActionResult, error) {
+			call.Params["paths"].([]interface{})[0] = "/etc/passwd"
+			return controller.NewActionResult(), nil
+		}
+	})
+
+	upload := controller.UploadFile(1, "/tmp/report.pdf")
+	_, err := c.ExecuteAction(&upload, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"/tmp/report.pdf"}, upload["upload_file"].(map[string]interface{})["paths"], "nested params of the model must not be changed")
+}
+
+func TestExecuteUploadFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<div><input id="single" type="file"></div>
+			<div><input id="multi" type="file" multiple></div>
+			<div><button onclick="document.getElementById('hidden').click()">Upload</button></div>
+			<div><input id="hidden" type="file" multiple style="display:none"></div>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	fileA := filepath.Join(dir, "a.txt")
+	fileB := filepath.Join(dir, "b.txt")
+	os.WriteFile(fileA, []byte("a"), 0644)
+	os.WriteFile(fileB, []byte("b"), 0644)
+	missing := filepath.Join(dir, "missing.txt")
+	availableFilePaths := []string{fileA, fileB, missing}
+
+	c, b, bc, page := initTest(t, true)
+	defer b.Close()
+	defer bc.Close()
+
+	bc.NavigateTo(server.URL)
+	bc.GetSession().CachedState = bc.GetState(false)
+
+	fileCount := func(id string) int {
+		count, err := page.Evaluate(`id => document.getElementById(id).files.length`, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count.(int)
+	}
+
+	uploadError := func(index int, paths []string) string {
+		result, err := c.ExecuteAction(&controller.ActModel{"upload_file": map[string]interface{}{"index": index, "paths": paths}}, bc, nil, nil, availableFilePaths)
+		if err != nil {
+			t.Fatalf("expected the refused upload to be reported as action result, got %v", err)
+		}
+		if result.Error == nil {
+			return ""
+		}
+		return *result.Error
+	}
+
+	if msg := uploadError(0, []string{"/etc/passwd"}); !strings.Contains(msg, "not in the available file paths") {
+		t.Errorf("expected error for a file that is not available, got %q", msg)
+	}
+	if msg := uploadError(0, []string{missing}); !strings.Contains(msg, "does not exist") {
+		t.Errorf("expected error for a missing file, got %q", msg)
+	}
+	if msg := uploadError(0, []string{fileA, fileB}); !strings.Contains(msg, "only one file") {
+		t.Errorf("expected error for multiple files on a single file input, got %q", msg)
+	}
+	if msg := uploadError(99, []string{fileA}); !strings.Contains(msg, "does not exist") {
+		t.Errorf("expected error for an unknown index, got %q", msg)
+	}
+
+	if _, err := c.ExecuteAction(&controller.ActModel{"upload_file": map[string]interface{}{"index": 1, "paths": []string{fileA, fileB}}}, bc, nil, nil, availableFilePaths); err != nil {
+		t.Fatal(err)
+	}
+	if count := fileCount("multi"); count != 2 {
+		t.Errorf("expected 2 files on the file input, got %d", count)
+	}
+
+	if _, err := c.ExecuteAction(&controller.ActModel{"upload_file": map[string]interface{}{"index": 2, "paths": []string{fileB}}}, bc, nil, nil, availableFilePaths); err != nil {
+		t.Fatal(err)
+	}
+	if count := fileCount("hidden"); count != 1 {
+		t.Errorf("expected 1 file uploaded through the file chooser, got %d", count)
+	}
+}
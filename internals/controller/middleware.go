@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"encoding/json"
+
+	"github.com/nerdface-ai/browser-use-go/pkg/browser"
+)
+
+// ActionCall is a single action execution as seen by the middleware chain
+type ActionCall struct {
+	Name           string
+	Params         map[string]interface{} // decoded params, sensitive data placeholders are not yet replaced
+	BrowserContext *browser.BrowserContext
+}
+
+// ActionHandler executes an action call and returns its result
+type ActionHandler func(call *ActionCall) (*ActionResult, error)
+
+// ActionMiddleware wraps an ActionHandler, e.g. for logging, metrics or policy checks.
+// A middleware can change the call before passing it on, change the result or error of next,
+// or short-circuit by returning without calling next at all.
+type ActionMiddleware func(next ActionHandler) ActionHandler
+
+// Use appends middlewares to the chain wrapping every action executed by the controller.
+// Middlewares run in the order they were added, the first one being the outermost.
+func (c *Controller) Use(middlewares ...ActionMiddleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// Build the handler chain around the given handler
+func (c *Controller) chain(handler ActionHandler) ActionHandler {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+	return handler
+}
+
+// Decode action params into a map. The map is a deep copy made through JSON, so middlewares changing
+// the params, also nested ones, do not change the action the model sent, which is kept in the history.
+func decodeParams(params interface{}) (map[string]interface{}, error) {
+	paramMap := map[string]interface{}{}
+	if params == nil {
+		return paramMap, nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &paramMap); err != nil {
+		return nil, err
+	}
+	return paramMap, nil
+}
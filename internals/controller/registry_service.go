@@ -121,6 +121,12 @@ func (r *Registry) replaceSensitiveData(argumentsInJson string, sensitiveData ma
 	return replaceSecrets(argumentsInJson)
 }
 
+// Validate an action against all registered actions, regardless of page filters
+func (r *Registry) ValidateAction(action *ActModel) error {
+	actionModel := &ActionModel{Actions: r.Registry.Actions}
+	return actionModel.ValidateAction(action)
+}
+
 func (r *Registry) CreateActionModel(includeActions []string, page playwright.Page) *ActionModel {
 	// Create model from registered actions, used by LLM APIs that support tool calling
 
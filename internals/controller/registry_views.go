@@ -3,9 +3,11 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/cloudwego/eino/components/tool"
@@ -16,6 +18,8 @@ import (
 
 type RegisteredAction struct {
 	Tool *tool.InvokableTool
+	// JSON schema of the params, used to validate actions before execution (empty if the params type can not be reflected)
+	ParamSchema string
 	// filters: provide specific domains or a function to determine whether the action should be available on the given page or not
 	Domains    []string // # e.g. ['*.google.com', 'www.bing.com', 'yahoo.*]
 	PageFilter func(playwright.Page) bool
@@ -33,13 +37,36 @@ func NewRegisteredAction[T, D any](
 	if err != nil {
 		return nil, err
 	}
+
+	paramSchema := ""
+	paramType := reflect.TypeOf((*T)(nil)).Elem()
+	if paramType.Kind() == reflect.Struct && paramType.Name() != "" {
+		paramSchema = GenerateSchema(new(T))
+	}
+
 	return &RegisteredAction{
-		Tool:       &customTool,
-		Domains:    domains,
-		PageFilter: pageFilter,
+		Tool:        &customTool,
+		ParamSchema: paramSchema,
+		Domains:     domains,
+		PageFilter:  pageFilter,
 	}, nil
 }
 
+// Validate params against the registered param schema
+func (ra *RegisteredAction) ValidateParams(params interface{}) error {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	paramMap, ok := params.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("params must be an object, got %T", params)
+	}
+	if ra.ParamSchema == "" {
+		return nil
+	}
+	return ValidateSchema(ra.ParamSchema, paramMap)
+}
+
 /*
 	example
 
@@ -98,6 +125,37 @@ type ActionModel struct {
 
 type ActModel map[string]interface{}
 
+// Get the name and params of the action
+func (am *ActModel) Action() (string, interface{}, error) {
+	if len(*am) != 1 {
+		names := make([]string, 0, len(*am))
+		for name := range *am {
+			names = append(names, name)
+		}
+		return "", nil, fmt.Errorf("expected exactly one action name per item, got %d %v", len(*am), names)
+	}
+	for name, params := range *am {
+		return name, params, nil
+	}
+	return "", nil, errors.New("empty action")
+}
+
+// Validate an action against the actions of this model and their param schemas
+func (m *ActionModel) ValidateAction(action *ActModel) error {
+	name, params, err := action.Action()
+	if err != nil {
+		return err
+	}
+	registered, ok := m.Actions[name]
+	if !ok {
+		return fmt.Errorf("action %q is not available", name)
+	}
+	if err := registered.ValidateParams(params); err != nil {
+		return fmt.Errorf("invalid params for %q: %s", name, err.Error())
+	}
+	return nil
+}
+
 // Get the index of the action
 func (am *ActModel) GetIndex() *int {
 	for _, params := range *am {
@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -51,7 +54,8 @@ func getBrowserContext(ctx context.Context) (*browser.BrowserContext, error) {
 }
 
 type Controller struct {
-	Registry *Registry
+	Registry    *Registry
+	middlewares []ActionMiddleware
 }
 
 func NewController() *Controller {
@@ -61,6 +65,7 @@ func NewController() *Controller {
 	RegisterAction(c, "done", "Complete task - with return text and if the task is finished (success=True) or not yet  completely finished (success=False), because last step is reached", c.Done, []string{}, nil)
 	RegisterAction(c, "click_element_by_index", "Click element by index", c.ClickElementByIndex, []string{}, nil)
 	RegisterAction(c, "input_text", "Input text into a input interactive element", c.InputText, []string{}, nil)
+	RegisterAction(c, "upload_file", "Upload files to the file input or upload button at the element index, only the available file paths can be used", c.UploadFile, []string{}, nil)
 	RegisterAction(c, "search_google", "Search the query in Google in the current tab, the query should be a search query like humans search in Google, concrete and not vague or super long. More the single most important items.", c.SearchGoogle, []string{}, nil)
 	RegisterAction(c, "go_to_url", "Navigate to URL in the current tab", c.GoToUrl, []string{}, nil)
 	RegisterAction(c, "go_back", "Go back to the previous page", c.GoBack, []string{}, nil)
@@ -104,31 +109,53 @@ func (c *Controller) ExecuteAction(
 	// context: Context | None,
 ) (*ActionResult, error) {
 	for actionName, actionParams := range *action {
-		buffer := &bytes.Buffer{}
-		encoder := json.NewEncoder(buffer)
-		encoder.SetEscapeHTML(false)
-		err := encoder.Encode(actionParams)
+		params, err := decodeParams(actionParams)
 		if err != nil {
 			return nil, err
 		}
-		ab := buffer.Bytes()
-		if len(ab) > 0 && ab[len(ab)-1] == '\n' {
-			ab = ab[:len(ab)-1]
+		call := &ActionCall{
+			Name:           actionName,
+			Params:         params,
+			BrowserContext: browserContext,
 		}
-		result, err := c.Registry.ExecuteAction(actionName, string(ab), browserContext, pageExtractionLlm, sensitiveData, availableFilePaths)
-		if err != nil {
-			return nil, err
-		}
-		var actionResult ActionResult
-		err = json.Unmarshal([]byte(result), &actionResult)
-		if err != nil {
-			return nil, err
-		}
-		return &actionResult, nil
+		handler := c.chain(func(call *ActionCall) (*ActionResult, error) {
+			return c.executeActionCall(call, pageExtractionLlm, sensitiveData, availableFilePaths)
+		})
+		return handler(call)
 	}
 	return NewActionResult(), nil
 }
 
+// Execute an action call through the registry, the innermost handler of the middleware chain
+func (c *Controller) executeActionCall(
+	call *ActionCall,
+	pageExtractionLlm model.ToolCallingChatModel,
+	sensitiveData map[string]string,
+	availableFilePaths []string,
+) (*ActionResult, error) {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(call.Params)
+	if err != nil {
+		return nil, err
+	}
+	ab := buffer.Bytes()
+	if len(ab) > 0 && ab[len(ab)-1] == '\n' {
+		ab = ab[:len(ab)-1]
+	}
+	result, err := c.Registry.ExecuteAction(call.Name, string(ab), call.BrowserContext, pageExtractionLlm, sensitiveData, availableFilePaths)
+	if err != nil {
+		return nil, err
+	}
+	var actionResult ActionResult
+	err = json.Unmarshal([]byte(result), &actionResult)
+	if err != nil {
+		return nil, err
+	}
+	return &actionResult, nil
+}
+
 func (c *Controller) Done(_ context.Context, params DoneAction) (*ActionResult, error) {
 	log.Debug("Done Action called")
 	actionResult := NewActionResult()
@@ -154,7 +181,7 @@ func (c *Controller) ClickElementByIndex(ctx context.Context, params ClickElemen
 
 	// if element has file uploader then dont click
 	if bc.IsFileUploader(elementNode, 3, 0) {
-		msg := fmt.Sprintf("Index %d - has an element which opens file upload dialog. To upload files please use a specific function to upload files", params.Index)
+		msg := fmt.Sprintf("Index %d - has an element which opens file upload dialog. To upload files please use the upload_file action", params.Index)
 		log.Info(msg)
 		actionResult := NewActionResult()
 		actionResult.ExtractedContent = &msg
@@ -170,6 +197,11 @@ func (c *Controller) ClickElementByIndex(ctx context.Context, params ClickElemen
 	msg := ""
 	if downloadPath != nil {
 		msg = fmt.Sprintf("💾  Downloaded file to %s", *downloadPath)
+		for _, download := range bc.Downloads() {
+			if download.Path == *downloadPath && download.Status == browser.DownloadInProgress {
+				msg = fmt.Sprintf("💾  Downloading file to %s, the download is still in progress", *downloadPath)
+			}
+		}
 	} else {
 		msg = fmt.Sprintf("🖱️  Clicked button with index %d: %s", params.Index, elementNode.GetAllTextTillNextClickableElement(-1))
 	}
@@ -213,6 +245,79 @@ func (c *Controller) InputText(ctx context.Context, params InputTextAction) (*Ac
 	return actionResult, nil
 }
 
+func (c *Controller) UploadFile(ctx context.Context, params UploadFileAction) (*ActionResult, error) {
+	bc, err := getBrowserContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// refused and failed uploads are reported to the model, which can pick another file or element
+	uploadError := func(errorMsg string) (*ActionResult, error) {
+		log.Info(errorMsg)
+		actionResult := NewActionResult()
+		actionResult.Error = &errorMsg
+		actionResult.IncludeInMemory = true
+		return actionResult, nil
+	}
+	if len(params.Paths) == 0 {
+		return uploadError("At least one file path is required")
+	}
+	availableFilePaths, _ := ctx.Value(availableFilePathsKey).([]string)
+	for _, path := range params.Paths {
+		if !slices.ContainsFunc(availableFilePaths, func(available string) bool {
+			return filepath.Clean(available) == filepath.Clean(path)
+		}) {
+			return uploadError(fmt.Sprintf("File %s is not in the available file paths", path))
+		}
+		if _, err := os.Stat(path); err != nil {
+			return uploadError(fmt.Sprintf("File %s does not exist", path))
+		}
+	}
+
+	elementNode, err := bc.GetDomElementByIndex(params.Index)
+	if err != nil {
+		return uploadError(err.Error())
+	}
+
+	if fileUploadNode := elementNode.GetFileUploadElement(true); fileUploadNode != nil {
+		_, isMultiple := fileUploadNode.Attributes["multiple"]
+		if len(params.Paths) > 1 && !isMultiple {
+			return uploadError(fmt.Sprintf("File input at index %d accepts only one file", params.Index))
+		}
+		locator := bc.GetLocateElement(fileUploadNode)
+		if locator == nil {
+			return uploadError(fmt.Sprintf("File input at index %d not found", params.Index))
+		}
+		if err := locator.First().SetInputFiles(params.Paths); err != nil {
+			return uploadError(fmt.Sprintf("Failed to upload files to index %d: %s", params.Index, err))
+		}
+	} else {
+		// custom upload buttons open a file chooser dialog
+		locator := bc.GetLocateElement(elementNode)
+		if locator == nil {
+			return uploadError(fmt.Sprintf("Element at index %d not found", params.Index))
+		}
+		fileChooser, err := bc.GetCurrentPage().ExpectFileChooser(func() error {
+			return locator.First().Click()
+		}, playwright.PageExpectFileChooserOptions{Timeout: playwright.Float(bc.Config.ClickTimeout * 1000)})
+		if err != nil {
+			return uploadError(fmt.Sprintf("No file input or upload dialog found at index %d", params.Index))
+		}
+		if len(params.Paths) > 1 && !fileChooser.IsMultiple() {
+			return uploadError(fmt.Sprintf("Upload dialog at index %d accepts only one file", params.Index))
+		}
+		if err := fileChooser.SetFiles(params.Paths); err != nil {
+			return uploadError(fmt.Sprintf("Failed to upload files to index %d: %s", params.Index, err))
+		}
+	}
+
+	msg := fmt.Sprintf("📁  Uploaded %s to index %d", strings.Join(params.Paths, ", "), params.Index)
+	log.Info(msg)
+	actionResult := NewActionResult()
+	actionResult.ExtractedContent = &msg
+	actionResult.IncludeInMemory = true
+	return actionResult, nil
+}
+
 func (c *Controller) SearchGoogle(ctx context.Context, params SearchGoogleAction) (*ActionResult, error) {
 	bc, err := getBrowserContext(ctx)
 	if err != nil {
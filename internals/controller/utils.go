@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"reflect"
+	"strings"
 
 	"github.com/invopop/jsonschema"
 	"github.com/xeipuuv/gojsonschema"
@@ -66,5 +67,9 @@ func ValidateSchema(schemaString string, data map[string]interface{}) error {
 	if validResult.Valid() {
 		return nil
 	}
-	return errors.New("invalid schema")
+	details := make([]string, 0, len(validResult.Errors()))
+	for _, resultError := range validResult.Errors() {
+		details = append(details, resultError.String())
+	}
+	return errors.New("invalid schema: " + strings.Join(details, ", "))
 }
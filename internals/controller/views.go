@@ -24,6 +24,11 @@ type InputTextAction struct {
 	Xpath *string `json:"xpath,omitempty" jsonschema:"anyof_type=string;null,default=null"`
 }
 
+type UploadFileAction struct {
+	Index int      `json:"index"`
+	Paths []string `json:"paths" jsonschema:"description=one or more of the available file paths"`
+}
+
 type DoneAction struct {
 	Text    string `json:"text"`
 	Success bool   `json:"success"`
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -75,10 +76,13 @@ func TestAgentGetNextAction(t *testing.T) {
 	}
 	task := "do google search to find images of Elon Musk's wife"
 	extendSystemMessage := "REMEMBER the most important RULE: ALWAYS open first a new tab and go first to url wikipedia.com no matter the task!!!"
-	ag := NewAgent(task, model, WithAgentSettings(AgentSettingsConfig{
+	ag, err := NewAgent(task, model, WithAgentSettings(AgentSettingsConfig{
 		"extend_system_message": &extendSystemMessage,
 		"planner_llm":           model,
 	}))
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	inputMessages := []*schema.Message{
 		{
@@ -155,10 +159,13 @@ func TestAgentSetup(t *testing.T) {
 	}
 	task := "do google search to find images of Elon Musk's wife"
 	extendSystemMessage := "REMEMBER the most important RULE: ALWAYS open first a new tab and go first to url wikipedia.com no matter the task!!!"
-	ag := NewAgent(task, model, WithAgentSettings(AgentSettingsConfig{
+	ag, err := NewAgent(task, model, WithAgentSettings(AgentSettingsConfig{
 		"extend_system_message": &extendSystemMessage,
 		"planner_llm":           model,
 	}), WithController(controller.NewController()))
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	s, _ := ag.AgentOutput.ToOpenAPIV3()
 	j, _ := json.Marshal(s)
@@ -184,10 +191,13 @@ func TestGetPromptDescription(t *testing.T) {
 	}
 	task := "do google search to find images of Elon Musk's wife"
 	extendSystemMessage := "REMEMBER the most important RULE: ALWAYS open first a new tab and go first to url wikipedia.com no matter the task!!!"
-	ag := NewAgent(task, model, WithAgentSettings(AgentSettingsConfig{
+	ag, err := NewAgent(task, model, WithAgentSettings(AgentSettingsConfig{
 		"extend_system_message": &extendSystemMessage,
 		"planner_llm":           model,
 	}), WithController(controller.NewController()))
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	result := ag.Controller.Registry.GetPromptDescription(nil)
 
@@ -231,7 +241,7 @@ func TestMultiAct(t *testing.T) {
 	session.CachedState = currentState
 	// for test ----------------------------------
 
-	ag := NewAgent(
+	ag, err := NewAgent(
 		task,
 		model,
 		WithAgentSettings(AgentSettingsConfig{
@@ -242,6 +252,9 @@ func TestMultiAct(t *testing.T) {
 		WithBrowserContext(bc),
 		WithController(c),
 	)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	actions := []*controller.ActModel{
 		{
@@ -272,3 +285,101 @@ func TestMultiAct(t *testing.T) {
 		t.Errorf("expected page url to be https://www.naver.com, got %s", pageUrl)
 	}
 }
+
+func TestValidateModelOutput(t *testing.T) {
+	ag := &Agent{
+		Controller: controller.NewController(),
+		Settings:   NewAgentSettings(AgentSettingsConfig{"max_actions_per_step": 2}),
+	}
+	ag.setupActionModels()
+
+	valid := &AgentOutput{Actions: []*controller.ActModel{
+		{"go_to_url": map[string]interface{}{"url": "https://example.com"}},
+		{"click_element_by_index": map[string]interface{}{"index": 1}},
+	}}
+	if err := ag.validateModelOutput(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	tooMany := &AgentOutput{Actions: []*controller.ActModel{
+		{"go_back": map[string]interface{}{}},
+		{"go_back": map[string]interface{}{}},
+		{"go_back": map[string]interface{}{}},
+	}}
+	err := ag.validateModelOutput(tooMany)
+	if err == nil || !strings.Contains(err.Error(), "too many actions: got 3, maximum is 2") {
+		t.Errorf("expected too many actions error, got %v", err)
+	}
+
+	invalid := &AgentOutput{Actions: []*controller.ActModel{
+		{"fly_to_the_moon": map[string]interface{}{}},
+		{"click_element_by_index": map[string]interface{}{"index": "one"}},
+	}}
+	err = ag.validateModelOutput(invalid)
+	var invalidOutput *InvalidModelOutputError
+	if !errors.As(err, &invalidOutput) {
+		t.Fatalf("expected InvalidModelOutputError, got %v", err)
+	}
+	if len(invalidOutput.Problems) != 2 {
+		t.Errorf("expected 2 problems, got %v", invalidOutput.Problems)
+	}
+	if strings.Contains(err.Error(), "\n") {
+		t.Errorf("error should be a single line to survive the state message, got %q", err.Error())
+	}
+
+	empty := &AgentOutput{}
+	if err := ag.validateModelOutput(empty); err == nil {
+		t.Error("expected error for empty action list")
+	}
+
+	// the last step only allows done
+	ag.AgentOutput = ag.DoneAgentOutput
+	click := &AgentOutput{Actions: []*controller.ActModel{{"click_element_by_index": map[string]interface{}{"index": 1}}}}
+	if err := ag.validateModelOutput(click); err == nil || !strings.Contains(err.Error(), "click_element_by_index") {
+		t.Errorf("expected click_element_by_index to be rejected on the last step, got %v", err)
+	}
+	done := &AgentOutput{Actions: []*controller.ActModel{{"done": map[string]interface{}{"text": "finished", "success": true}}}}
+	if err := ag.validateModelOutput(done); err != nil {
+		t.Errorf("expected done to be valid on the last step, got %v", err)
+	}
+}
+
+func TestNewAgentInvalidInitialActions(t *testing.T) {
+	_, err := NewAgent("task", nil, WithInitialActions([]map[string]interface{}{
+		controller.GoToURL("https://example.com"),
+		{"go_to_ulr": map[string]interface{}{"url": "https://example.com"}},
+	}))
+	if err == nil || !strings.Contains(err.Error(), "invalid initial action 2") {
+		t.Errorf("expected invalid initial action error, got %v", err)
+	}
+
+	_, err = NewAgent("task", nil, WithInitialActions([]map[string]interface{}{
+		{"click_element_by_index": map[string]interface{}{"index": "first"}},
+	}))
+	if err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
+
+func TestNewAgentInvalidBrowserConfig(t *testing.T) {
+	_, err := NewAgent("task", nil, WithBrowserConfig(browser.BrowserConfig{"cookie_file": "cookies.json"}))
+	if err == nil || !strings.Contains(err.Error(), "cookies_file") {
+		t.Errorf("expected unknown key error with suggestion, got %v", err)
+	}
+
+	_, err = NewAgent("task", nil, WithBrowserSettings(browser.NewBrowserSettings(browser.WithBrowserClass("opera"))))
+	if err == nil {
+		t.Error("expected error for invalid browser class")
+	}
+}
+
+func TestDecideDialogBudgetExhausted(t *testing.T) {
+	bt := newBudgetTracker(Budget{MaxInputTokens: 1000})
+	bt.record(600, 20, time.Second)
+	// the model is not asked once the budget is exhausted
+	ag := &Agent{Task: "test", budget: bt}
+	response := ag.decideDialog(browser.DialogInfo{Type: "confirm", Message: "Continue?"})
+	if response.Accept {
+		t.Error("expected the dialog to be dismissed")
+	}
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ErrBudgetExhausted is returned for model calls besides the steps once the budget of the run is exhausted
+var ErrBudgetExhausted = errors.New("budget exhausted")
+
+// Budget caps the resources a single Agent.Run may consume.
+// A zero value for any limit means that limit is not enforced.
+type Budget struct {
+	MaxInputTokens  int
+	MaxOutputTokens int
+	MaxCost         float64
+	MaxDuration     time.Duration
+
+	// Prices per 1M tokens, used to estimate the cost of a run
+	InputTokenPrice  float64
+	OutputTokenPrice float64
+}
+
+// budgetTracker accumulates usage of a run and decides when the agent has to finish up.
+// Dialogs are decided from playwright's event goroutine, so the usage is guarded by a mutex.
+type budgetTracker struct {
+	mu        sync.Mutex
+	budget    Budget
+	startTime time.Time
+
+	inputTokens  int
+	outputTokens int
+
+	lastInputTokens  int
+	lastOutputTokens int
+	lastStepDuration time.Duration
+}
+
+func newBudgetTracker(budget Budget) *budgetTracker {
+	return &budgetTracker{
+		budget:    budget,
+		startTime: time.Now(),
+	}
+}
+
+// record adds the usage of one model call
+func (bt *budgetTracker) record(inputTokens int, outputTokens int, duration time.Duration) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	bt.inputTokens += inputTokens
+	bt.outputTokens += outputTokens
+	bt.lastInputTokens = inputTokens
+	bt.lastOutputTokens = outputTokens
+	bt.lastStepDuration = duration
+}
+
+// recordSideCall adds the usage of a model call outside of the steps, e.g. to decide about a dialog.
+// It does not change the size of the last call the forecasts are based on.
+func (bt *budgetTracker) recordSideCall(inputTokens int, outputTokens int) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	bt.inputTokens += inputTokens
+	bt.outputTokens += outputTokens
+}
+
+// Estimated cost for the given amount of tokens
+func (bt *budgetTracker) cost(inputTokens int, outputTokens int) float64 {
+	return (float64(inputTokens)*bt.budget.InputTokenPrice + float64(outputTokens)*bt.budget.OutputTokenPrice) / 1_000_000
+}
+
+// Cost spent so far
+func (bt *budgetTracker) Cost() float64 {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	return bt.cost(bt.inputTokens, bt.outputTokens)
+}
+
+// wouldExceed checks whether spending the given amount on top of the current usage overruns any limit.
+// It returns a human readable reason for the first limit that would be overrun.
+func (bt *budgetTracker) wouldExceed(inputTokens int, outputTokens int, duration time.Duration) (bool, string) {
+	b := bt.budget
+	if b.MaxInputTokens > 0 && bt.inputTokens+inputTokens > b.MaxInputTokens {
+		return true, fmt.Sprintf("input tokens %d/%d", bt.inputTokens, b.MaxInputTokens)
+	}
+	if b.MaxOutputTokens > 0 && bt.outputTokens+outputTokens > b.MaxOutputTokens {
+		return true, fmt.Sprintf("output tokens %d/%d", bt.outputTokens, b.MaxOutputTokens)
+	}
+	if cost := bt.cost(bt.inputTokens, bt.outputTokens); b.MaxCost > 0 && cost+bt.cost(inputTokens, outputTokens) > b.MaxCost {
+		return true, fmt.Sprintf("cost %.4f/%.4f", cost, b.MaxCost)
+	}
+	if b.MaxDuration > 0 && time.Since(bt.startTime)+duration > b.MaxDuration {
+		return true, fmt.Sprintf("duration %s/%s", time.Since(bt.startTime).Round(time.Second), b.MaxDuration)
+	}
+	return false, ""
+}
+
+// Exhausted reports whether another model call of the size of the last one can not be afforded anymore
+func (bt *budgetTracker) Exhausted() (bool, string) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	return bt.wouldExceed(bt.lastInputTokens, bt.lastOutputTokens, bt.lastStepDuration)
+}
+
+// NearlyExhausted reports whether the upcoming call has to be the last one.
+// nextInputTokens is the estimated size of the upcoming call; the forecast leaves room for it and one more call.
+func (bt *budgetTracker) NearlyExhausted(nextInputTokens int) (bool, string) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	inputTokens := max(nextInputTokens, bt.lastInputTokens)
+	return bt.wouldExceed(2*inputTokens, 2*bt.lastOutputTokens, 2*bt.lastStepDuration)
+}
+
+// Generate with a model besides the steps, e.g. the planner, the validator or the page extraction, counting the usage
+// towards the budget. Fails with ErrBudgetExhausted without calling the model once the budget is exhausted.
+func (ag *Agent) generateSideCall(ctx context.Context, llm model.BaseChatModel, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if ag.budget == nil {
+		return llm.Generate(ctx, input, opts...)
+	}
+	if exhausted, reason := ag.budget.Exhausted(); exhausted {
+		return nil, fmt.Errorf("%w: %s", ErrBudgetExhausted, reason)
+	}
+	response, err := llm.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if response.ResponseMeta != nil && response.ResponseMeta.Usage != nil {
+		ag.budget.recordSideCall(response.ResponseMeta.Usage.PromptTokens, response.ResponseMeta.Usage.CompletionTokens)
+		return response, nil
+	}
+	// estimated like the messages of the steps when the model reports no usage
+	inputTokens := 0
+	for _, msg := range input {
+		inputTokens += ag.MessageManager.countTokens(msg)
+	}
+	ag.budget.recordSideCall(inputTokens, ag.MessageManager.countTokens(response))
+	return response, nil
+}
+
+// budgetedModel counts the calls of a model used by the controller, i.e. the page extraction, towards the budget
+type budgetedModel struct {
+	model.ToolCallingChatModel
+	ag *Agent
+}
+
+func (m *budgetedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return m.ag.generateSideCall(ctx, m.ToolCallingChatModel, input, opts...)
+}
+
+// Streams the generated message at once, so its usage is counted as well
+func (m *budgetedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	response, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{response}), nil
+}
+
+func (m *budgetedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	llm, err := m.ToolCallingChatModel.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetedModel{ToolCallingChatModel: llm, ag: m.ag}, nil
+}
+
+// The page extraction model passed to the controller, counted towards the budget of the run
+func (ag *Agent) pageExtractionLLM() model.ToolCallingChatModel {
+	if ag.budget == nil || ag.Settings.PageExtractionLLM == nil {
+		return ag.Settings.PageExtractionLLM
+	}
+	return &budgetedModel{ToolCallingChatModel: ag.Settings.PageExtractionLLM, ag: ag}
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestBudgetTrackerTokens(t *testing.T) {
+	bt := newBudgetTracker(Budget{MaxInputTokens: 1000, MaxOutputTokens: 100})
+
+	if exhausted, _ := bt.Exhausted(); exhausted {
+		t.Error("expected fresh budget not to be exhausted")
+	}
+	if nearly, _ := bt.NearlyExhausted(300); nearly {
+		t.Error("expected budget with room for two calls not to be nearly exhausted")
+	}
+
+	bt.record(300, 20, time.Second)
+	if nearly, reason := bt.NearlyExhausted(300); nearly {
+		t.Errorf("expected budget not to be nearly exhausted, got %s", reason)
+	}
+
+	bt.record(300, 20, time.Second)
+	nearly, reason := bt.NearlyExhausted(300)
+	if !nearly {
+		t.Error("expected budget to be nearly exhausted after 600/1000 input tokens")
+	}
+	if reason != "input tokens 600/1000" {
+		t.Errorf("unexpected reason: %s", reason)
+	}
+	if exhausted, _ := bt.Exhausted(); exhausted {
+		t.Error("expected budget to still allow one more call")
+	}
+
+	bt.record(300, 20, time.Second)
+	if exhausted, _ := bt.Exhausted(); !exhausted {
+		t.Error("expected budget to be exhausted after 900/1000 input tokens")
+	}
+}
+
+func TestBudgetTrackerCost(t *testing.T) {
+	bt := newBudgetTracker(Budget{
+		MaxCost:          1.0,
+		InputTokenPrice:  1000, // per 1M tokens
+		OutputTokenPrice: 4000,
+	})
+	bt.record(200, 50, 0) // 0.2 + 0.2
+
+	if cost := bt.Cost(); cost < 0.399 || cost > 0.401 {
+		t.Errorf("expected cost 0.4, got %f", cost)
+	}
+	if nearly, _ := bt.NearlyExhausted(200); !nearly {
+		t.Error("expected cost budget to be nearly exhausted")
+	}
+	if exhausted, _ := bt.Exhausted(); exhausted {
+		t.Error("expected cost budget to allow one more call")
+	}
+}
+
+func TestBudgetTrackerDuration(t *testing.T) {
+	bt := newBudgetTracker(Budget{MaxDuration: time.Hour})
+	if exhausted, _ := bt.Exhausted(); exhausted {
+		t.Error("expected duration budget not to be exhausted")
+	}
+
+	bt.startTime = time.Now().Add(-50 * time.Minute)
+	bt.record(0, 0, 6*time.Minute)
+	if nearly, _ := bt.NearlyExhausted(0); !nearly {
+		t.Error("expected duration budget to be nearly exhausted")
+	}
+	if exhausted, _ := bt.Exhausted(); exhausted {
+		t.Error("expected duration budget to allow one more step")
+	}
+}
+
+func TestBudgetTrackerSideCall(t *testing.T) {
+	bt := newBudgetTracker(Budget{MaxInputTokens: 1000})
+	bt.record(300, 20, time.Second)
+	bt.recordSideCall(500, 10)
+
+	if bt.inputTokens != 800 || bt.outputTokens != 30 {
+		t.Errorf("expected the side call to count towards the usage, got %d/%d tokens", bt.inputTokens, bt.outputTokens)
+	}
+	if bt.lastInputTokens != 300 || bt.lastOutputTokens != 20 {
+		t.Error("expected the side call not to change the forecast of the next step")
+	}
+	if exhausted, _ := bt.Exhausted(); !exhausted {
+		t.Error("expected the budget to be exhausted after 800/1000 input tokens")
+	}
+}
+
+// usageModel answers with a fixed usage and counts its calls
+type usageModel struct {
+	calls int
+}
+
+func (m *usageModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	return &schema.Message{
+		Role:         schema.Assistant,
+		Content:      "{}",
+		ResponseMeta: &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 400, CompletionTokens: 10}},
+	}, nil
+}
+
+func (m *usageModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not supported")
+}
+
+func (m *usageModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func TestGenerateSideCallBudget(t *testing.T) {
+	llm := &usageModel{}
+	ag := &Agent{budget: newBudgetTracker(Budget{MaxInputTokens: 1000})}
+	ag.budget.record(400, 20, time.Second)
+
+	// page extraction goes through the controller, it is counted by the wrapped model
+	extraction := &budgetedModel{ToolCallingChatModel: llm, ag: ag}
+	if _, err := extraction.Generate(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if ag.budget.inputTokens != 800 || ag.budget.outputTokens != 30 {
+		t.Errorf("expected the side call to be recorded, got %d/%d tokens", ag.budget.inputTokens, ag.budget.outputTokens)
+	}
+
+	_, err := ag.generateSideCall(context.Background(), llm, nil)
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if llm.calls != 1 {
+		t.Errorf("expected the model not to be called once the budget is exhausted, got %d calls", llm.calls)
+	}
+}
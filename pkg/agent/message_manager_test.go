@@ -83,3 +83,87 @@ func TestAddStateMessage(t *testing.T) {
 		t.Errorf("Expected state message to include %s, got %s", testUrl, messages[2].Content)
 	}
 }
+
+func TestAddStateMessageDownloads(t *testing.T) {
+	messageManager := SampleMessageManager()
+	state := browser.BrowserState{
+		Url:         "https://example.com",
+		ElementTree: &dom.DOMElementNode{TagName: "div", Attributes: map[string]string{}, Children: []dom.DOMBaseNode{}, Xpath: "//div"},
+		SelectorMap: &dom.SelectorMap{},
+		Downloads: []*browser.DownloadInfo{
+			{SuggestedFilename: "report.pdf", Path: "/tmp/downloads/report.pdf", Status: browser.DownloadCompleted, Size: 1024, MimeType: "application/pdf"},
+			{SuggestedFilename: "data.csv", Status: browser.DownloadInProgress},
+		},
+	}
+	messageManager.AddStateMessage(&state, nil, nil, true)
+
+	messages := messageManager.GetMessages()
+	content := messages[len(messages)-1].Content
+	for _, expected := range []string{"Downloaded files:", "/tmp/downloads/report.pdf (application/pdf, 1024 bytes)", "data.csv (downloading)"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected state message to include %q, got %s", expected, content)
+		}
+	}
+}
+
+func TestAddStateMessageDialogs(t *testing.T) {
+	messageManager := SampleMessageManager()
+	state := browser.BrowserState{
+		Url:         "https://example.com/form",
+		ElementTree: &dom.DOMElementNode{TagName: "div", Attributes: map[string]string{}, Children: []dom.DOMBaseNode{}, Xpath: "//div"},
+		SelectorMap: &dom.SelectorMap{},
+		Dialogs: []*browser.DialogInfo{
+			{Type: "alert", Message: "Please enter your email", Url: "https://example.com/form", Accepted: true},
+			{Type: "confirm", Message: "Leave this page?", Url: "https://example.com/form", Accepted: false},
+		},
+	}
+	messageManager.AddStateMessage(&state, nil, nil, true)
+
+	messages := messageManager.GetMessages()
+	content := messages[len(messages)-1].Content
+	for _, expected := range []string{
+		"Dialogs opened since the last step:",
+		`alert dialog "Please enter your email" on https://example.com/form was accepted`,
+		`confirm dialog "Leave this page?" on https://example.com/form was dismissed`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected state message to include %q, got %s", expected, content)
+		}
+	}
+}
+
+func TestAddStateMessageBrowserErrors(t *testing.T) {
+	messageManager := SampleMessageManager()
+	state := browser.BrowserState{
+		Url:           "https://example.com/checkout",
+		ElementTree:   &dom.DOMElementNode{TagName: "div", Attributes: map[string]string{}, Children: []dom.DOMBaseNode{}, Xpath: "//div"},
+		SelectorMap:   &dom.SelectorMap{},
+		BrowserErrors: []string{"[http_error] POST https://example.com/api/order 500 Internal Server Error"},
+	}
+	messageManager.AddStateMessage(&state, nil, nil, true)
+
+	messages := messageManager.GetMessages()
+	content := messages[len(messages)-1].Content
+	expected := "Browser errors since the last step:\n- [http_error] POST https://example.com/api/order 500 Internal Server Error"
+	if !strings.Contains(content, expected) {
+		t.Errorf("expected state message to include %q, got %s", expected, content)
+	}
+}
+
+func TestAddStateMessageViewport(t *testing.T) {
+	messageManager := SampleMessageManager()
+	state := browser.BrowserState{
+		Url:         "https://example.com",
+		ElementTree: &dom.DOMElementNode{TagName: "div", Attributes: map[string]string{}, Children: []dom.DOMBaseNode{}, Xpath: "//div"},
+		SelectorMap: &dom.SelectorMap{},
+		Viewport:    &browser.ViewportInfo{Width: 390, Height: 664, DeviceScaleFactor: 3, IsMobile: true},
+	}
+	messageManager.AddStateMessage(&state, nil, nil, true)
+
+	messages := messageManager.GetMessages()
+	content := messages[len(messages)-1].Content
+	expected := "Current url: https://example.com\nCurrent viewport: 390x664 (mobile, device scale factor 3)\nAvailable tabs:"
+	if !strings.Contains(content, expected) {
+		t.Errorf("expected state message to include %q, got %s", expected, content)
+	}
+}
@@ -114,18 +114,36 @@ func (amp *AgentMessagePrompt) GetUserMessage(useVision bool) *schema.Message {
 	timeStr := time.Now().Format("2006-01-02 15:04")
 	stepInfoDescription += fmt.Sprintf("Current date and time: %s", timeStr)
 
+	var eventsDescription string
+	if len(amp.State.Downloads) > 0 {
+		eventsDescription = fmt.Sprintf("Downloaded files:\n%s\n", browser.DownloadsToString(amp.State.Downloads))
+	}
+	if len(amp.State.Dialogs) > 0 {
+		eventsDescription += fmt.Sprintf("Dialogs opened since the last step:\n%s\n", browser.DialogsToString(amp.State.Dialogs))
+	}
+	if len(amp.State.BrowserErrors) > 0 {
+		eventsDescription += fmt.Sprintf("Browser errors since the last step:\n- %s\n", strings.Join(amp.State.BrowserErrors, "\n- "))
+	}
+
+	var viewportDescription string
+	if amp.State.Viewport != nil {
+		viewportDescription = fmt.Sprintf("Current viewport: %s\n", amp.State.Viewport.String())
+	}
+
 	stateDescription := fmt.Sprintf(`
 [Task history memory ends]
 [Current state starts here]
 The following is one-time information - if you need to remember it write it to memory:
 Current url: %s
-Available tabs:
+%sAvailable tabs:
 %s
-Interactive elements from top layer of the current page inside the viewport:
+%sInteractive elements from top layer of the current page inside the viewport:
 %s
 %s`,
 		amp.State.Url,
+		viewportDescription,
 		browser.TabsToString(amp.State.Tabs),
+		eventsDescription,
 		elementText,
 		stepInfoDescription,
 	)
@@ -56,6 +56,9 @@ type Agent struct {
 
 	UnfilteredActions string
 	InitialActions    []*controller.ActModel
+
+	budget         *budgetTracker
+	lastTokenUsage *schema.TokenUsage
 }
 
 type AgentOption func(*AgentOptions)
@@ -72,13 +75,27 @@ func WithBrowser(b *browser.Browser) AgentOption {
 	}
 }
 
+// Use a new browser with a map configuration, see browser.BrowserConfigFromMap.
+// Unknown keys and invalid values make NewAgent return an error.
 func WithBrowserConfig(b browser.BrowserConfig) AgentOption {
 	return func(o *AgentOptions) {
-		browserConfig := browser.NewBrowserConfig()
-		for key, value := range b {
-			browserConfig[key] = value
+		settings, err := browser.BrowserConfigFromMap(b)
+		if err != nil {
+			o.err = errors.Join(o.err, fmt.Errorf("invalid browser config: %w", err))
+			return
+		}
+		o.browserInst = browser.NewBrowserWithSettings(settings)
+	}
+}
+
+// Use a new browser with typed settings. Invalid settings make NewAgent return an error.
+func WithBrowserSettings(settings *browser.BrowserSettings) AgentOption {
+	return func(o *AgentOptions) {
+		if err := settings.Validate(); err != nil {
+			o.err = errors.Join(o.err, fmt.Errorf("invalid browser config: %w", err))
+			return
 		}
-		o.browserInst = browser.NewBrowser(browserConfig)
+		o.browserInst = browser.NewBrowserWithSettings(settings)
 	}
 }
 
@@ -100,6 +117,8 @@ func WithSensitiveData(data map[string]string) AgentOption {
 	}
 }
 
+// Actions executed before the first step. They are validated against the controller registry in NewAgent,
+// use the builders of the controller package (e.g. controller.GoToURL) to construct them.
 func WithInitialActions(actions []map[string]interface{}) AgentOption {
 	return func(o *AgentOptions) {
 		o.initialActions = actions
@@ -158,6 +177,9 @@ type AgentOptions struct {
 
 	// Inject sate
 	injectedAgentState *AgentState
+
+	// Errors of the options, returned by NewAgent
+	err error
 }
 
 /*
@@ -177,11 +199,14 @@ func NewAgent(
 	llm model.ToolCallingChatModel,
 	options ...AgentOption,
 	// Memory settings
-) *Agent {
+) (*Agent, error) {
 	opts := &AgentOptions{settings: NewAgentSettings(AgentSettingsConfig{})}
 	for _, opt := range options {
 		opt(opts)
 	}
+	if opts.err != nil {
+		return nil, opts.err
+	}
 	if opts.settings.PageExtractionLLM == nil {
 		opts.settings.PageExtractionLLM = llm
 	}
@@ -211,7 +236,11 @@ func NewAgent(
 	// Action setup
 	agent.setupActionModels()
 	// TODO(LOW): self._set_browser_use_version_and_source()
-	agent.InitialActions = agent.convertInitialActions(opts.initialActions)
+	initialActions, err := agent.convertInitialActions(opts.initialActions)
+	if err != nil {
+		return nil, err
+	}
+	agent.InitialActions = initialActions
 
 	// Model setup
 	agent.setModelNames()
@@ -260,24 +289,34 @@ func NewAgent(
 		opts.browserContext = opts.browserInst.NewContext()
 	}
 	agent.BrowserContext = opts.browserContext
+	if !agent.InjectedBrowserContext {
+		// an injected context may be shared, its owner names the videos
+		agent.BrowserContext.SetVideoName(agent.State.AgentId)
+	}
+	if agent.BrowserContext.Config.DialogPolicy == browser.DialogPolicyAsk {
+		agent.BrowserContext.SetDialogHandler(agent.decideDialog)
+	}
 
 	// Callbacks
 	agent.RegisterNewStepCallback = opts.registerNewStepCallback
 	agent.RegisterDoneCallback = opts.registerDoneCallback
 	agent.RegisterExternalAgentStatusRaiseErrorCallback = opts.registerExternalAgentStatusRaiseErrorCallback
 
-	return agent
+	return agent, nil
 }
 
-// Convert dictionary-based actions to ActModel instances
-func (ag *Agent) convertInitialActions(actions []map[string]interface{}) []*controller.ActModel {
+// Convert dictionary-based actions to ActModel instances, validated against the registry
+func (ag *Agent) convertInitialActions(actions []map[string]interface{}) ([]*controller.ActModel, error) {
 	initialActions := make([]*controller.ActModel, len(actions))
 	for i, action := range actions {
 		actModel := &controller.ActModel{}
 		*actModel = action
+		if err := ag.Controller.Registry.ValidateAction(actModel); err != nil {
+			return nil, fmt.Errorf("invalid initial action %d: %w", i+1, err)
+		}
 		initialActions[i] = actModel
 	}
-	return initialActions
+	return initialActions, nil
 }
 
 func (ag *Agent) setMessageContext() *string {
@@ -390,6 +429,10 @@ func (ag *Agent) step(stepInfo *AgentStepInfo) error {
 	// Execute one step of the task
 	log.Infof("📍 Step %d\n", ag.State.NSteps)
 	stepStartTime := time.Now().UnixNano()
+	pageLoadWaitStart := ag.BrowserContext.PageLoadWaitTime()
+	if err := ag.BrowserContext.NextTraceChunk(fmt.Sprintf("step_%d", ag.State.NSteps)); err != nil {
+		log.Warnf("❌ %s", err)
+	}
 
 	browserState := ag.BrowserContext.GetState(true)
 	activePage := ag.BrowserContext.GetCurrentPage()
@@ -459,7 +502,16 @@ func (ag *Agent) step(stepInfo *AgentStepInfo) error {
 		}
 	}
 
-	if stepInfo != nil && stepInfo.IsLastStep() {
+	budgetNearlyExhausted := false
+	if ag.budget != nil {
+		var reason string
+		budgetNearlyExhausted, reason = ag.budget.NearlyExhausted(ag.MessageManager.State.History.CurrentTokens)
+		if budgetNearlyExhausted {
+			log.Infof("💰 Budget nearly exhausted (%s)", reason)
+		}
+	}
+
+	if (stepInfo != nil && stepInfo.IsLastStep()) || budgetNearlyExhausted {
 		// Add last step warning if needed
 		msg := "Now comes your last step. Use only the \"done\" action now. No other actions - so here your action sequence must have length 1."
 		msg += "\nIf the task is not yet fully finished as requested by the user, set success in \"done\" to false! E.g. if not all steps are fully completed."
@@ -476,10 +528,50 @@ func (ag *Agent) step(stepInfo *AgentStepInfo) error {
 	inputMessages := ag.MessageManager.GetMessages()
 	tokens := ag.MessageManager.State.History.CurrentTokens
 
+	callStartTime := time.Now()
 	modelOutput, err := ag.getNextAction(inputMessages)
+	if err == nil {
+		err = ag.validateModelOutput(modelOutput)
+	}
+
+	outputTokens := 0
+	if modelOutput != nil {
+		outputTokens = ag.MessageManager.countTextTokens(modelOutput.ToString())
+	}
+	if ag.lastTokenUsage != nil {
+		tokens = ag.lastTokenUsage.PromptTokens
+		outputTokens = ag.lastTokenUsage.CompletionTokens
+	}
+	if ag.budget != nil {
+		ag.budget.record(tokens, outputTokens, time.Since(callStartTime))
+	}
+
 	if err != nil {
 		ag.MessageManager.RemoveLastStateMessage()
-		return errors.New("failed to get next action")
+		var invalidOutput *InvalidModelOutputError
+		if !errors.As(err, &invalidOutput) {
+			return errors.New("failed to get next action")
+		}
+
+		// Report the problems to the model instead of executing anything
+		log.Errorf("❌ %s", invalidOutput.Error())
+		ag.State.NSteps++
+		ag.State.ConsecutiveFailures++
+		errStr := invalidOutput.Error()
+		ag.State.LastResult = []*controller.ActionResult{{Error: &errStr, IncludeInMemory: true}}
+		if browserState != nil {
+			metaData := &StepMetadata{
+				StepNumber:    ag.State.NSteps,
+				StepStartTime: float64(stepStartTime),
+				StepEndTime:   float64(time.Now().UnixNano()),
+				InputTokens:   tokens,
+				OutputTokens:  outputTokens,
+
+				PageLoadWaitTime: (ag.BrowserContext.PageLoadWaitTime() - pageLoadWaitStart).Seconds(),
+			}
+			ag.makeHistoryItem(nil, browserState, ag.State.LastResult, metaData)
+		}
+		return nil
 	}
 
 	// Check again for paused/stopped state after getting model output
@@ -547,6 +639,9 @@ func (ag *Agent) step(stepInfo *AgentStepInfo) error {
 			StepStartTime: float64(stepStartTime),
 			StepEndTime:   float64(time.Now().UnixNano()),
 			InputTokens:   tokens,
+			OutputTokens:  outputTokens,
+
+			PageLoadWaitTime: (ag.BrowserContext.PageLoadWaitTime() - pageLoadWaitStart).Seconds(),
 		}
 		ag.makeHistoryItem(modelOutput, browserState, result, metaData)
 	}
@@ -608,7 +703,11 @@ func (ag *Agent) runPlanner() (*string, error) {
 	// TODO: deepseek or other model support
 
 	// Get planner output
-	response, err := ag.Settings.PlannerLLM.Generate(context.Background(), plannerMessages)
+	response, err := ag.generateSideCall(context.Background(), ag.Settings.PlannerLLM, plannerMessages)
+	if errors.Is(err, ErrBudgetExhausted) {
+		log.Warnf("💰 Skipping the planner: %s", err)
+		return nil, nil
+	}
 	if err != nil {
 		log.Error("Failed to invoke planner: %s", err.Error())
 		return nil, err
@@ -630,6 +729,7 @@ func (ag *Agent) getNextAction(inputMessages []*schema.Message) (*AgentOutput, e
 	// TODO(MID): support deepseek
 	// TODO(MID): support other models like gemini, hugginface
 
+	ag.lastTokenUsage = nil
 	toolLLM, err := ag.LLM.WithTools([]*schema.ToolInfo{ag.AgentOutput})
 	if err != nil {
 		log.Error(err)
@@ -641,6 +741,9 @@ func (ag *Agent) getNextAction(inputMessages []*schema.Message) (*AgentOutput, e
 		log.Error(err)
 		return nil, err
 	}
+	if response.ResponseMeta != nil && response.ResponseMeta.Usage != nil {
+		ag.lastTokenUsage = response.ResponseMeta.Usage
+	}
 
 	toolCalls := response.ToolCalls
 	if len(toolCalls) == 0 {
@@ -661,23 +764,42 @@ func (ag *Agent) getNextAction(inputMessages []*schema.Message) (*AgentOutput, e
 
 	err = json.Unmarshal([]byte(toolCallArgs), &parsed)
 	if err != nil {
-		log.Debug("failed to unmarshal tool call args: %s", toolCallArgs)
-		// currentState := map[string]interface{}{
-		// 	"page_summary":             "Processing tool call",
-		// 	"evaluation_previous_goal": "Executing action",
-		// 	"memory":                   "Using tool call",
-		// 	"next_goal":                fmt.Sprintf("Execute %s", toolCallName),
-		// }
-
-		// // Create action from tool call
-		// action := map[string]interface{}{
-		// 	toolCallName: toolCallArgs,
-		// }
+		log.Debugf("failed to unmarshal tool call args: %s", toolCallArgs)
+		return nil, &InvalidModelOutputError{Problems: []string{"could not parse tool call arguments: " + err.Error()}}
 	}
 
 	return &parsed, nil
 }
 
+// Validate the model output against the available actions before anything is executed
+func (ag *Agent) validateModelOutput(modelOutput *AgentOutput) error {
+	problems := []string{}
+	if len(modelOutput.Actions) == 0 {
+		problems = append(problems, "no actions given, at least one action is required")
+	}
+	if ag.Settings.MaxActionsPerStep > 0 && len(modelOutput.Actions) > ag.Settings.MaxActionsPerStep {
+		problems = append(problems, fmt.Sprintf("too many actions: got %d, maximum is %d per step", len(modelOutput.Actions), ag.Settings.MaxActionsPerStep))
+	}
+	// only done is available on the last step and once the budget is nearly exhausted
+	actionModel := ag.ActionModel
+	if ag.AgentOutput == ag.DoneAgentOutput {
+		actionModel = ag.DoneActionModel
+	}
+	for i, action := range modelOutput.Actions {
+		if action == nil {
+			problems = append(problems, fmt.Sprintf("action %d: empty action", i+1))
+			continue
+		}
+		if err := actionModel.ValidateAction(action); err != nil {
+			problems = append(problems, fmt.Sprintf("action %d: %s", i+1, err.Error()))
+		}
+	}
+	if len(problems) > 0 {
+		return &InvalidModelOutputError{Problems: problems}
+	}
+	return nil
+}
+
 func (ag *Agent) raiseIfStoppedOrPaused() error {
 	if ag.RegisterExternalAgentStatusRaiseErrorCallback != nil {
 		log.Debug("raiseIfStoppedOrPaused")
@@ -713,6 +835,7 @@ type agentRunOptions struct {
 	onStepStart func(*Agent)
 	onStepEnd   func(*Agent)
 	autoClose   bool
+	budget      *Budget
 }
 
 // WithMaxSteps sets the maximum number of steps for Agent.Run
@@ -743,6 +866,14 @@ func WithAutoClose(autoClose bool) AgentRunOption {
 	}
 }
 
+// WithBudget caps the tokens, estimated cost and wall-clock time of Agent.Run.
+// When the budget is about to run out, the agent is forced to call done with partial results.
+func WithBudget(budget Budget) AgentRunOption {
+	return func(o *agentRunOptions) {
+		o.budget = &budget
+	}
+}
+
 // Run executes the agent for up to maxSteps (default 10), using functional options for callbacks
 func (ag *Agent) Run(opts ...AgentRunOption) (*AgentHistoryList, error) {
 	options := agentRunOptions{
@@ -759,8 +890,17 @@ func (ag *Agent) Run(opts ...AgentRunOption) (*AgentHistoryList, error) {
 	// TODO(LOW): implement verification llm (Wait for verification task to complete if it exists)
 	// TODO(LOW): implement generate gif
 
+	ag.budget = nil
+	if options.budget != nil {
+		ag.budget = newBudgetTracker(*options.budget)
+	}
+
 	ag.logAgentRun()
 
+	if _, err := ag.BrowserContext.Start(context.Background()); err != nil {
+		return nil, err
+	}
+
 	// Execute initial actions if provided
 	if len(ag.InitialActions) > 0 {
 		result, err := ag.multiAct(ag.InitialActions, false)
@@ -771,6 +911,7 @@ func (ag *Agent) Run(opts ...AgentRunOption) (*AgentHistoryList, error) {
 	}
 
 	stepCheck := 0
+	finalStep := false
 	for step := 0; step < options.maxSteps; step++ {
 		if ag.State.Paused {
 			// TODO(LOW): implement signal handler
@@ -787,6 +928,14 @@ func (ag *Agent) Run(opts ...AgentRunOption) (*AgentHistoryList, error) {
 			break
 		}
 
+		if ag.budget != nil && !finalStep {
+			// the step only offers done while the budget is (nearly) exhausted
+			if exhausted, reason := ag.budget.Exhausted(); exhausted {
+				log.Errorf("❌ Budget exhausted (%s), finishing with a final done step", reason)
+				finalStep = true
+			}
+		}
+
 		for ag.State.Paused {
 			time.Sleep(200 * time.Millisecond)
 			if ag.State.Stopped {
@@ -813,7 +962,7 @@ func (ag *Agent) Run(opts ...AgentRunOption) (*AgentHistoryList, error) {
 		}
 
 		if ag.State.History.IsDone() {
-			if ag.ValidateLLM != nil && step < options.maxSteps-1 {
+			if ag.ValidateLLM != nil && step < options.maxSteps-1 && !finalStep {
 				if !ag.validateOutput() {
 					continue
 				}
@@ -822,12 +971,17 @@ func (ag *Agent) Run(opts ...AgentRunOption) (*AgentHistoryList, error) {
 			ag.logCompletion()
 			break
 		}
+		if finalStep {
+			log.Error("❌ Stopping due to exhausted budget")
+			break
+		}
 		stepCheck++
 	}
 	if stepCheck == options.maxSteps {
 		log.Info("❌ Failed to complete task in maximum steps")
 	}
 
+	ag.RecordArtifacts()
 	return ag.State.History, nil
 }
 
@@ -837,6 +991,8 @@ func (ag *Agent) Close() {
 	var err error
 	if ag.BrowserContext != nil && !ag.InjectedBrowserContext {
 		ag.BrowserContext.Close()
+		// traces and videos are only saved when the context is closed
+		ag.RecordArtifacts()
 	}
 	if ag.Browser != nil && !ag.InjectedBrowser {
 		err = ag.Browser.Close()
@@ -846,6 +1002,15 @@ func (ag *Agent) Close() {
 	}
 }
 
+// Add the files recorded by the browser context to the history.
+// Close does this for a context created by the agent. Callers injecting a context close it themselves,
+// which completes its traces and videos, and call RecordArtifacts afterwards to add them to the history.
+func (ag *Agent) RecordArtifacts() {
+	ag.State.History.HarPaths = ag.BrowserContext.HarPaths()
+	ag.State.History.TracePaths = ag.BrowserContext.TracePaths()
+	ag.State.History.VideoPaths = ag.BrowserContext.VideoPaths()
+}
+
 // Execute multiple actions
 func (ag *Agent) multiAct(
 	actions []*controller.ActModel,
@@ -865,7 +1030,8 @@ func (ag *Agent) multiAct(
 
 	for i, action := range actions {
 		if action.GetIndex() != nil && i != 0 {
-			newState := ag.BrowserContext.GetState(false)
+			// the browser logs and dialogs of earlier actions stay for the state of the next step
+			newState := ag.BrowserContext.PeekState()
 			newSelectorMap := newState.SelectorMap
 
 			// Detect index change after previous action
@@ -906,7 +1072,7 @@ func (ag *Agent) multiAct(
 		}
 
 		ag.raiseIfStoppedOrPaused()
-		result, err := ag.Controller.ExecuteAction(action, ag.BrowserContext, ag.Settings.PageExtractionLLM, ag.SensitiveData, ag.Settings.AvailableFilePaths)
+		result, err := ag.Controller.ExecuteAction(action, ag.BrowserContext, ag.pageExtractionLLM(), ag.SensitiveData, ag.Settings.AvailableFilePaths)
 		if err != nil {
 			return nil, err
 			// TODO(LOW): implement signal handler error
@@ -923,7 +1089,7 @@ func (ag *Agent) multiAct(
 			break
 		}
 
-		time.Sleep(500 * time.Millisecond) // ag.BrowserContext.Config.WaitBetweenActions
+		time.Sleep(ag.BrowserContext.WaitBetweenActions())
 	}
 
 	return results, nil
@@ -947,6 +1113,7 @@ func (ag *Agent) makeHistoryItem(
 		Title:             browserState.Title,
 		Tabs:              browserState.Tabs,
 		InteractedElement: interactedElements,
+		BrowserLogs:       browserState.BrowserLogs,
 	}
 
 	historyItem := &AgentHistory{
@@ -989,7 +1156,7 @@ func (ag *Agent) validateOutput() bool {
 
 	var msg []*schema.Message
 	if ag.BrowserContext.Session != nil {
-		state := ag.BrowserContext.GetState(false)
+		state := ag.BrowserContext.PeekState()
 		content := NewAgentMessagePrompt(
 			state,
 			ag.State.LastResult,
@@ -1005,7 +1172,11 @@ func (ag *Agent) validateOutput() bool {
 		return true
 	}
 
-	response, err := ag.ValidateLLM.Generate(context.Background(), msg)
+	response, err := ag.generateSideCall(context.Background(), ag.ValidateLLM, msg)
+	if errors.Is(err, ErrBudgetExhausted) {
+		log.Warnf("💰 Skipping the validation of the output: %s", err)
+		return true
+	}
 	if err != nil {
 		log.Error("Failed to invoke validator: %s", err.Error())
 		return false
@@ -1030,6 +1201,45 @@ func (ag *Agent) validateOutput() bool {
 	return isValid
 }
 
+type dialogDecisionOutput struct {
+	Accept     bool   `json:"accept"`
+	PromptText string `json:"prompt_text"`
+}
+
+// Ask the model how to handle a javascript dialog, dismissing it if the model gives no usable answer
+func (ag *Agent) decideDialog(dialog browser.DialogInfo) browser.DialogResponse {
+	systemMsg := "You are an agent who interacts with a browser. " +
+		"Task: " + ag.Task + ". " +
+		"The page opened a javascript dialog, decide if it should be accepted or dismissed to complete the task. " +
+		"Return a JSON object with 2 keys: accept and prompt_text. " +
+		"accept is a boolean that indicates if the dialog should be accepted. " +
+		"prompt_text is the text to enter if the dialog is a prompt, otherwise an empty string." +
+		` example: {"accept": true, "prompt_text": ""}`
+	dialogMsg := fmt.Sprintf("Url: %s\nDialog type: %s\nMessage: %s", dialog.Url, dialog.Type, dialog.Message)
+	if dialog.Type == "prompt" {
+		dialogMsg += "\nDefault value: " + dialog.DefaultValue
+	}
+
+	response, err := ag.generateSideCall(context.Background(), ag.LLM, []*schema.Message{
+		{Content: systemMsg, Role: schema.System},
+		{Content: dialogMsg, Role: schema.User},
+	})
+	if errors.Is(err, ErrBudgetExhausted) {
+		log.Warnf("💰 Dismissing the dialog without asking the model: %s", err)
+		return browser.DialogResponse{Accept: false}
+	}
+	if err != nil {
+		log.Errorf("Failed to ask the model about a dialog: %s", err.Error())
+		return browser.DialogResponse{Accept: false}
+	}
+	var parsed dialogDecisionOutput
+	if err := json.Unmarshal([]byte(response.Content), &parsed); err != nil {
+		log.Errorf("Failed to parse dialog decision: %s", err.Error())
+		return browser.DialogResponse{Accept: false}
+	}
+	return browser.DialogResponse{Accept: parsed.Accept, PromptText: parsed.PromptText}
+}
+
 // Log the completion of the task
 func (ag *Agent) logCompletion() {
 	log.Info("✅ Task completed")
@@ -1041,6 +1251,10 @@ func (ag *Agent) logCompletion() {
 
 	totalTokens := ag.State.History.TotalInputTokens()
 	log.Infof("📝 Total input tokens used (approximate): %d", totalTokens)
+	log.Infof("📝 Total output tokens used (approximate): %d", ag.State.History.TotalOutputTokens())
+	if ag.budget != nil && ag.budget.budget.MaxCost > 0 {
+		log.Infof("💰 Estimated cost: %.4f/%.4f", ag.budget.Cost(), ag.budget.budget.MaxCost)
+	}
 
 	if ag.RegisterDoneCallback != nil {
 		ag.RegisterDoneCallback(ag.State.History)
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/nerdface-ai/browser-use-go/internals/controller"
 	"github.com/nerdface-ai/browser-use-go/internals/dom"
@@ -144,6 +145,16 @@ func (ao *AgentOutput) ToString() string {
 	return string(b)
 }
 
+// InvalidModelOutputError is returned when the model output can not be parsed or executed.
+// The problems are reported back to the model so it can correct its next output.
+type InvalidModelOutputError struct {
+	Problems []string
+}
+
+func (e *InvalidModelOutputError) Error() string {
+	return "Invalid model output: " + strings.Join(e.Problems, "; ")
+}
+
 func ToolInfoWithCustomActions(customActions *controller.ActionModel) *schema.ToolInfo {
 	actionSchemas := map[string]*openapi3.SchemaRef{}
 	ctx := context.Background()
@@ -210,7 +221,10 @@ type StepMetadata struct {
 	StepStartTime float64
 	StepEndTime   float64
 	InputTokens   int
+	OutputTokens  int
 	StepNumber    int
+	// Seconds spent waiting for pages to load during the step
+	PageLoadWaitTime float64
 }
 
 // Calculate step duration in seconds
@@ -291,7 +305,10 @@ func (ah *AgentHistory) ModelDump() map[string]interface{} {
 }
 
 type AgentHistoryList struct {
-	History []*AgentHistory `json:"history"`
+	History    []*AgentHistory `json:"history"`
+	HarPaths   []string        `json:"har_paths,omitempty"`   // HAR files recorded by the browser context, see save_har_path
+	TracePaths []string        `json:"trace_paths,omitempty"` // playwright traces saved by the browser context, see trace_path
+	VideoPaths []string        `json:"video_paths,omitempty"` // videos recorded by the browser context, see record_video_dir
 }
 
 func (ahl *AgentHistoryList) LastResult() *ActionResult {
@@ -336,6 +353,27 @@ func (ahl *AgentHistoryList) TotalInputTokens() int {
 	return totalTokens
 }
 
+func (ahl *AgentHistoryList) TotalOutputTokens() int {
+	totalTokens := 0
+	for _, history := range ahl.History {
+		if history.Metadata != nil {
+			totalTokens += history.Metadata.OutputTokens
+		}
+	}
+	return totalTokens
+}
+
+// Browser errors and warnings collected during all steps
+func (ahl *AgentHistoryList) BrowserLogs() []*browser.BrowserLogEntry {
+	logs := []*browser.BrowserLogEntry{}
+	for _, history := range ahl.History {
+		if history.State != nil {
+			logs = append(logs, history.State.BrowserLogs...)
+		}
+	}
+	return logs
+}
+
 func (ahl *AgentHistoryList) ModelDump() map[string]interface{} {
 	histories := []map[string]interface{}{}
 	for _, history := range ahl.History {
@@ -0,0 +1,230 @@
+package agenttest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/nerdface-ai/browser-use-go/pkg/agent"
+	"github.com/nerdface-ai/browser-use-go/pkg/agenttest"
+	"github.com/nerdface-ai/browser-use-go/pkg/browser"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func htmlTestDir(t *testing.T) string {
+	_, filename, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot get current file info")
+	}
+	return filepath.Join(filepath.Dir(filename), "..", "..", "html_test")
+}
+
+func TestScriptedModelQueue(t *testing.T) {
+	m := agenttest.NewScriptedModel(
+		agenttest.Output(agenttest.Action("go_to_url", map[string]interface{}{"url": "https://example.com"})),
+		agenttest.Done("finished", true),
+	)
+	ctx := context.Background()
+	input := []*schema.Message{{Role: schema.User, Content: "Current url: about:blank"}}
+
+	for _, expected := range []string{"go_to_url", "done"} {
+		response, err := m.Generate(ctx, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.ToolCalls) != 1 || response.ToolCalls[0].Function.Name != "AgentOutput" {
+			t.Fatalf("expected one AgentOutput tool call, got %v", response.ToolCalls)
+		}
+		var output agent.AgentOutput
+		if err := json.Unmarshal([]byte(response.ToolCalls[0].Function.Arguments), &output); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := (*output.Actions[0])[expected]; !ok {
+			t.Errorf("expected action %s, got %v", expected, output.Actions[0])
+		}
+	}
+
+	if _, err := m.Generate(ctx, input); !errors.Is(err, agenttest.ErrScriptExhausted) {
+		t.Errorf("expected ErrScriptExhausted, got %v", err)
+	}
+	if len(m.Calls()) != 3 {
+		t.Errorf("expected 3 recorded calls, got %d", len(m.Calls()))
+	}
+}
+
+func TestScriptedModelRules(t *testing.T) {
+	m := agenttest.NewScriptedModel(agenttest.Done("fallback", false)).
+		When(agenttest.URLContains("/form"), agenttest.Done("form", true))
+	toolModel, err := m.WithTools([]*schema.ToolInfo{{Name: "AgentOutput"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := toolModel.Generate(context.Background(), []*schema.Message{
+		{Role: schema.User, Content: "Current url: http://127.0.0.1/form\nAvailable tabs:"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output agent.AgentOutput
+	if err := json.Unmarshal([]byte(response.ToolCalls[0].Function.Arguments), &output); err != nil {
+		t.Fatal(err)
+	}
+	if (*output.Actions[0])["done"].(map[string]interface{})["text"] != "form" {
+		t.Errorf("expected rule output, got %v", output.Actions[0])
+	}
+	if len(m.Calls()) != 1 {
+		t.Errorf("expected calls to be shared with the tool model, got %d", len(m.Calls()))
+	}
+}
+
+func TestFixtureServer(t *testing.T) {
+	s := agenttest.NewFixtureServerFromDir(t, htmlTestDir(t))
+	s.AddPage("/hello", "<h1>hello</h1>")
+
+	for path, expected := range map[string]int{
+		"/hello":            http.StatusOK,
+		"/select_page.html": http.StatusOK,
+		"/missing":          http.StatusNotFound,
+	} {
+		resp, err := http.Get(s.PageURL(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("%s: expected status %d, got %d (%s)", path, expected, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestAgentRunOffline(t *testing.T) {
+	s := agenttest.NewFixtureServerFromDir(t, htmlTestDir(t))
+
+	m := agenttest.NewScriptedModel(
+		agenttest.Output(agenttest.Action("go_to_url", map[string]interface{}{"url": s.PageURL("/select_page.html")})),
+	).When(agenttest.URLContains("select_page"), agenttest.Output(
+		agenttest.Action("select_dropdown_option", map[string]interface{}{"index": 1, "text": "Dog"}),
+		agenttest.Action("done", map[string]interface{}{"text": "Selected Dog", "success": true}),
+	))
+
+	ag, err := agent.NewAgent("choose dog as pet", m, agent.WithBrowserConfig(browser.BrowserConfig{
+		"headless": true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := ag.Run(agent.WithMaxSteps(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agenttest.AssertDone(t, history, true)
+	agenttest.AssertActions(t, history, "go_to_url", "select_dropdown_option", "done")
+	agenttest.AssertVisited(t, history, "/select_page.html")
+	agenttest.AssertNoErrors(t, history)
+	agenttest.AssertFinalResult(t, history, "Dog")
+}
+
+func TestAgentRunBudgetExhaustedFinishesWithDone(t *testing.T) {
+	s := agenttest.NewFixtureServerFromDir(t, htmlTestDir(t))
+
+	// the budget is exhausted by the first call, which is rejected as only done is offered
+	m := agenttest.NewScriptedModel(
+		agenttest.Output(agenttest.Action("go_to_url", map[string]interface{}{"url": s.PageURL("/select_page.html")})),
+		agenttest.Done("ran out of budget", false),
+	)
+	ag, err := agent.NewAgent("choose dog as pet", m, agent.WithBrowserConfig(browser.BrowserConfig{
+		"headless": true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := ag.Run(agent.WithMaxSteps(5), agent.WithBudget(agent.Budget{MaxInputTokens: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agenttest.AssertDone(t, history, false)
+	agenttest.AssertActions(t, history, "done")
+	if len(m.Calls()) != 2 {
+		t.Errorf("expected one final done step after the budget was exhausted, got %d model calls", len(m.Calls()))
+	}
+}
+
+func TestAgentRunReportsDialogOfEarlierAction(t *testing.T) {
+	s := agenttest.NewFixtureServer(t, map[string]string{
+		"/dialog.html": `<html><body>
+			<button onclick="console.error('save failed'); alert('saved')">Save</button>
+			<button>Next</button>
+		</body></html>`,
+	})
+
+	// the state between the two clicks must not consume the alert and the error of the first one
+	m := agenttest.NewScriptedModel(
+		agenttest.Output(agenttest.Action("go_to_url", map[string]interface{}{"url": s.PageURL("/dialog.html")})),
+	).When(agenttest.StateContains(`alert dialog "saved"`),
+		agenttest.Done("alert reported", true),
+	).When(agenttest.URLContains("dialog.html"), agenttest.Output(
+		agenttest.Action("click_element_by_index", map[string]interface{}{"index": 0}),
+		agenttest.Action("click_element_by_index", map[string]interface{}{"index": 1}),
+	))
+
+	ag, err := agent.NewAgent("save and continue", m, agent.WithBrowserConfig(browser.BrowserConfig{
+		"headless": true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := ag.Run(agent.WithMaxSteps(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agenttest.AssertDone(t, history, true)
+	agenttest.AssertActions(t, history, "go_to_url", "click_element_by_index", "click_element_by_index", "done")
+	logs := history.BrowserLogs()
+	if len(logs) != 1 || logs[0].Type != browser.LogConsoleError || logs[0].Message != "save failed" {
+		t.Errorf("expected the console error of the first click in the history, got %v", logs)
+	}
+}
+
+func TestAgentRecordArtifactsOfInjectedContext(t *testing.T) {
+	s := agenttest.NewFixtureServerFromDir(t, htmlTestDir(t))
+
+	b := browser.NewBrowserWithSettings(browser.NewBrowserSettings(browser.WithHeadless(true)))
+	defer b.Close()
+	dir := t.TempDir()
+	bc := b.NewContext(browser.WithRecordVideo(dir, nil))
+	bc.SetVideoName("shared")
+
+	m := agenttest.NewScriptedModel(
+		agenttest.Output(agenttest.Action("go_to_url", map[string]interface{}{"url": s.PageURL("/select_page.html")})),
+		agenttest.Done("opened", true),
+	)
+	ag, err := agent.NewAgent("open the page", m, agent.WithBrowser(b), agent.WithBrowserContext(bc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := ag.Run(agent.WithMaxSteps(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag.Close()
+	if len(history.VideoPaths) != 0 {
+		t.Fatalf("expected no videos before the injected context is closed, got %v", history.VideoPaths)
+	}
+
+	bc.Close()
+	ag.RecordArtifacts()
+	if len(history.VideoPaths) != 1 || history.VideoPaths[0] != filepath.Join(dir, "shared_tab0.webm") {
+		t.Errorf("expected the video named by the owner of the context, got %v", history.VideoPaths)
+	}
+}
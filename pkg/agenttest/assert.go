@@ -0,0 +1,90 @@
+package agenttest
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nerdface-ai/browser-use-go/pkg/agent"
+)
+
+// ActionNames returns the names of all executed actions in order
+func ActionNames(history *agent.AgentHistoryList) []string {
+	names := []string{}
+	for _, h := range history.History {
+		if h.ModelOutput == nil {
+			continue
+		}
+		// only actions with a result were executed
+		for i, action := range h.ModelOutput.Actions {
+			if i >= len(h.Result) {
+				break
+			}
+			for name := range *action {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// AssertDone fails the test if the run did not finish with done and the expected success
+func AssertDone(t testing.TB, history *agent.AgentHistoryList, success bool) {
+	t.Helper()
+	if history == nil || !history.IsDone() {
+		t.Errorf("expected agent to be done")
+		return
+	}
+	if s := history.IsSuccessful(); s == nil || *s != success {
+		t.Errorf("expected success to be %v, got %v", success, s)
+	}
+}
+
+// AssertActions fails the test if the executed actions differ from names
+func AssertActions(t testing.TB, history *agent.AgentHistoryList, names ...string) {
+	t.Helper()
+	if got := ActionNames(history); !slices.Equal(got, names) {
+		t.Errorf("expected actions %v, got %v", names, got)
+	}
+}
+
+// AssertVisited fails the test if no step was taken on a url containing url
+func AssertVisited(t testing.TB, history *agent.AgentHistoryList, url string) {
+	t.Helper()
+	visited := []string{}
+	for _, h := range history.History {
+		if h.State == nil {
+			continue
+		}
+		if strings.Contains(h.State.Url, url) {
+			return
+		}
+		visited = append(visited, h.State.Url)
+	}
+	t.Errorf("expected %s to be visited, got %v", url, visited)
+}
+
+// AssertNoErrors fails the test if any action returned an error
+func AssertNoErrors(t testing.TB, history *agent.AgentHistoryList) {
+	t.Helper()
+	for i, h := range history.History {
+		for _, r := range h.Result {
+			if r.Error != nil {
+				t.Errorf("step %d failed: %s", i+1, *r.Error)
+			}
+		}
+	}
+}
+
+// AssertFinalResult fails the test if the last extracted content does not contain text
+func AssertFinalResult(t testing.TB, history *agent.AgentHistoryList, text string) {
+	t.Helper()
+	last := history.LastResult()
+	if last == nil || last.ExtractedContent == nil {
+		t.Errorf("expected final result to contain %q, got none", text)
+		return
+	}
+	if !strings.Contains(*last.ExtractedContent, text) {
+		t.Errorf("expected final result to contain %q, got %q", text, *last.ExtractedContent)
+	}
+}
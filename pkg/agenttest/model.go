@@ -0,0 +1,212 @@
+// Package agenttest makes Agent.Run flows testable offline and deterministically
+// with a scripted model, a local fixture server and assertions on the history.
+package agenttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nerdface-ai/browser-use-go/internals/controller"
+	"github.com/nerdface-ai/browser-use-go/pkg/agent"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ErrScriptExhausted is returned when the scripted model has no output left for a call
+var ErrScriptExhausted = errors.New("scripted model has no more outputs")
+
+// Matcher decides whether a rule applies to the messages sent to the model
+type Matcher func(messages []*schema.Message) bool
+
+// Rule returns Output whenever Match applies to the current messages
+type Rule struct {
+	Match  Matcher
+	Output *agent.AgentOutput
+}
+
+type scriptState struct {
+	mu      sync.Mutex
+	outputs []*agent.AgentOutput
+	rules   []Rule
+	calls   [][]*schema.Message
+}
+
+// ScriptedModel is a model.ToolCallingChatModel that answers with predetermined AgentOutput tool calls.
+// Rules are checked first, in the order they were added; if none matches, the next queued output is returned.
+type ScriptedModel struct {
+	state *scriptState
+	tools []*schema.ToolInfo
+}
+
+var _ model.ToolCallingChatModel = (*ScriptedModel)(nil)
+
+func NewScriptedModel(outputs ...*agent.AgentOutput) *ScriptedModel {
+	return &ScriptedModel{
+		state: &scriptState{outputs: outputs},
+	}
+}
+
+// Then queues outputs returned in order when no rule matches
+func (m *ScriptedModel) Then(outputs ...*agent.AgentOutput) *ScriptedModel {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	m.state.outputs = append(m.state.outputs, outputs...)
+	return m
+}
+
+// When adds a rule returning output every time matcher applies
+func (m *ScriptedModel) When(matcher Matcher, output *agent.AgentOutput) *ScriptedModel {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	m.state.rules = append(m.state.rules, Rule{Match: matcher, Output: output})
+	return m
+}
+
+// Calls returns the messages of every call made to the model
+func (m *ScriptedModel) Calls() [][]*schema.Message {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	return append([][]*schema.Message{}, m.state.calls...)
+}
+
+// Tools returns the tools bound with WithTools
+func (m *ScriptedModel) Tools() []*schema.ToolInfo {
+	return m.tools
+}
+
+func (m *ScriptedModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return &ScriptedModel{state: m.state, tools: tools}, nil
+}
+
+func (m *ScriptedModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	output, err := m.next(input)
+	if err != nil {
+		return nil, err
+	}
+	args, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+
+	promptChars := 0
+	for _, msg := range input {
+		promptChars += len(messageText(msg))
+	}
+	usage := &schema.TokenUsage{
+		PromptTokens:     promptChars / 3,
+		CompletionTokens: len(args) / 3,
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	return &schema.Message{
+		Role: schema.Assistant,
+		ToolCalls: []schema.ToolCall{
+			{
+				ID:   strconv.Itoa(len(m.Calls())),
+				Type: "tool_call",
+				Function: schema.FunctionCall{
+					Name:      "AgentOutput",
+					Arguments: string(args),
+				},
+			},
+		},
+		ResponseMeta: &schema.ResponseMeta{FinishReason: "tool_calls", Usage: usage},
+	}, nil
+}
+
+func (m *ScriptedModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *ScriptedModel) next(input []*schema.Message) (*agent.AgentOutput, error) {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	m.state.calls = append(m.state.calls, input)
+
+	for _, rule := range m.state.rules {
+		if rule.Match(input) {
+			return rule.Output, nil
+		}
+	}
+	if len(m.state.outputs) == 0 {
+		return nil, ErrScriptExhausted
+	}
+	output := m.state.outputs[0]
+	m.state.outputs = m.state.outputs[1:]
+	return output, nil
+}
+
+// Output builds an AgentOutput executing the given actions
+func Output(actions ...*controller.ActModel) *agent.AgentOutput {
+	return &agent.AgentOutput{
+		CurrentState: &agent.AgentBrain{
+			EvaluationPreviousGoal: "Unknown - scripted",
+			Memory:                 "scripted",
+			NextGoal:               "scripted",
+		},
+		Actions: actions,
+	}
+}
+
+// Action builds a single action with its params
+func Action(name string, params map[string]interface{}) *controller.ActModel {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return &controller.ActModel{name: params}
+}
+
+// Done builds an AgentOutput that finishes the task
+func Done(text string, success bool) *agent.AgentOutput {
+	return Output(Action("done", map[string]interface{}{"text": text, "success": success}))
+}
+
+// StateContains matches when the latest state message contains text
+func StateContains(text string) Matcher {
+	return func(messages []*schema.Message) bool {
+		return strings.Contains(lastUserText(messages), text)
+	}
+}
+
+// URLContains matches when the current url of the latest state message contains text
+func URLContains(text string) Matcher {
+	return func(messages []*schema.Message) bool {
+		for _, line := range strings.Split(lastUserText(messages), "\n") {
+			if url, ok := strings.CutPrefix(line, "Current url: "); ok {
+				return strings.Contains(url, text)
+			}
+		}
+		return false
+	}
+}
+
+func lastUserText(messages []*schema.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == schema.User {
+			return messageText(messages[i])
+		}
+	}
+	return ""
+}
+
+func messageText(msg *schema.Message) string {
+	if len(msg.MultiContent) == 0 {
+		return msg.Content
+	}
+	text := ""
+	for _, part := range msg.MultiContent {
+		if part.Type == schema.ChatMessagePartTypeText {
+			text += part.Text
+		}
+	}
+	return text
+}
@@ -0,0 +1,113 @@
+package agenttest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nerdface-ai/browser-use-go/pkg/agent"
+	"github.com/nerdface-ai/browser-use-go/pkg/agenttest"
+	"github.com/nerdface-ai/browser-use-go/pkg/browser"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Find the pids of processes with the given argument on their command line
+func processesWithArg(t *testing.T, arg string) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		if slices.Contains(strings.Split(string(cmdline), "\x00"), arg) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// Find the pids of the playwright drivers started by this test process, exited drivers have no command line
+func driverProcesses(t *testing.T) []int {
+	var pids []int
+	for _, pid := range processesWithArg(t, "run-driver") {
+		stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+		if err != nil {
+			continue
+		}
+		// the parent pid follows the command name in parentheses and the state
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		if len(fields) > 1 && fields[1] == strconv.Itoa(os.Getpid()) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+func TestAgentCloseStopsProcesses(t *testing.T) {
+	pw, err := playwright.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryPath := pw.Chromium.ExecutablePath()
+	pw.Stop()
+
+	marker := "--agenttest-marker=" + strconv.Itoa(os.Getpid())
+	ag, err := agent.NewAgent("close", agenttest.NewScriptedModel(), agent.WithBrowserSettings(browser.NewBrowserSettings(
+		browser.WithHeadless(true),
+		browser.WithBrowserBinaryPath(binaryPath),
+		browser.WithExtraBrowserArgs(marker),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ag.BrowserContext.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pids := processesWithArg(t, marker)
+	if len(pids) == 0 {
+		t.Fatal("expected a running chrome process")
+	}
+	driverPids := driverProcesses(t)
+	if len(driverPids) == 0 {
+		t.Fatal("expected a running playwright driver")
+	}
+
+	ag.Close()
+
+	// killed processes are reaped asynchronously by init
+	for i := 0; i < 50 && len(processesWithArg(t, marker)) > 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if remaining := processesWithArg(t, marker); len(remaining) > 0 {
+		t.Errorf("expected no chrome processes after close, got %v", remaining)
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(-pid, 0); err == nil {
+			t.Errorf("expected process group %d to be gone", pid)
+		}
+	}
+	if ag.Browser.Playwright != nil {
+		t.Error("expected playwright to be stopped")
+	}
+	for i := 0; i < 50 && len(driverProcesses(t)) > 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if remaining := driverProcesses(t); len(remaining) > 0 {
+		t.Errorf("expected the playwright driver %v to be gone, got %v", driverPids, remaining)
+	}
+}
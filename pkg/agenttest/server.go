@@ -0,0 +1,68 @@
+package agenttest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// FixtureServer serves static HTML pages for offline end-to-end tests
+type FixtureServer struct {
+	*httptest.Server
+	mu    sync.RWMutex
+	pages map[string]string
+	dir   string
+}
+
+// NewFixtureServer starts a server for the given pages (path -> html) and closes it when the test ends
+func NewFixtureServer(t testing.TB, pages map[string]string) *FixtureServer {
+	s := &FixtureServer{pages: map[string]string{}}
+	for path, html := range pages {
+		s.AddPage(path, html)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// NewFixtureServerFromDir starts a server that also serves every file of dir, e.g. html_test/select_page.html as /select_page.html
+func NewFixtureServerFromDir(t testing.TB, dir string) *FixtureServer {
+	s := NewFixtureServer(t, nil)
+	s.dir = dir
+	return s
+}
+
+// AddPage registers or replaces the page served at path
+func (s *FixtureServer) AddPage(path string, html string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages["/"+strings.TrimPrefix(path, "/")] = html
+}
+
+// PageURL returns the absolute url of path on this server
+func (s *FixtureServer) PageURL(path string) string {
+	return s.URL + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (s *FixtureServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	html, ok := s.pages[r.URL.Path]
+	s.mu.RUnlock()
+	if ok {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(html))
+		return
+	}
+	if s.dir != "" {
+		path := filepath.Join(s.dir, filepath.FromSlash(filepath.Clean("/"+r.URL.Path)))
+		if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+			http.ServeFile(w, r, path)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
@@ -0,0 +1,207 @@
+package browser
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+type BrowserLogType string
+
+const (
+	LogConsoleError   BrowserLogType = "console_error"
+	LogConsoleWarning BrowserLogType = "console_warning"
+	LogPageError      BrowserLogType = "page_error"     // uncaught exception
+	LogRequestFailed  BrowserLogType = "request_failed" // network failure, no response
+	LogHttpError      BrowserLogType = "http_error"     // response with status 4xx or 5xx
+	LogCrash          BrowserLogType = "crash"
+)
+
+const (
+	MAX_BROWSER_LOGS_PER_PAGE   = 100 // distinct entries kept per page between two states
+	MAX_BROWSER_LOG_LENGTH      = 500 // characters of a message, longer messages are truncated
+	MAX_BROWSER_ERRORS_IN_STATE = 10  // entries in the digest of BrowserState.BrowserErrors
+)
+
+// A problem reported by a page, repeated occurrences are counted instead of recorded again
+type BrowserLogEntry struct {
+	Type    BrowserLogType `json:"type"`
+	Message string         `json:"message"`
+	Url     string         `json:"url"` // url of the page
+	Count   int            `json:"count"`
+	Time    time.Time      `json:"time"` // first occurrence
+}
+
+func (le *BrowserLogEntry) String() string {
+	s := fmt.Sprintf("[%s] %s", le.Type, le.Message)
+	if le.Count > 1 {
+		s += fmt.Sprintf(" (x%d)", le.Count)
+	}
+	return s
+}
+
+// Digest of the entries for the state message, errors before warnings
+func BrowserLogsDigest(entries []*BrowserLogEntry) []string {
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b *BrowserLogEntry) int {
+		isWarning := func(entry *BrowserLogEntry) int {
+			if entry.Type == LogConsoleWarning {
+				return 1
+			}
+			return 0
+		}
+		return cmp.Compare(isWarning(a), isWarning(b))
+	})
+	digest := []string{}
+	for i, entry := range sorted {
+		if i == MAX_BROWSER_ERRORS_IN_STATE {
+			digest = append(digest, fmt.Sprintf("... and %d more", len(sorted)-i))
+			break
+		}
+		digest = append(digest, entry.String())
+	}
+	return digest
+}
+
+type pageLog struct {
+	entries []*BrowserLogEntry
+	index   map[string]*BrowserLogEntry
+	dropped int
+}
+
+// Collects console errors and warnings, uncaught exceptions, failed requests and crashes of
+// all pages of a context until they are reported
+type browserLogCollector struct {
+	mu    sync.Mutex
+	pages map[playwright.Page]*pageLog
+	order []playwright.Page
+}
+
+func newBrowserLogCollector() *browserLogCollector {
+	return &browserLogCollector{pages: make(map[playwright.Page]*pageLog)}
+}
+
+// Collect the logs of all current and future pages of the context
+func (lc *browserLogCollector) attach(context playwright.BrowserContext) {
+	context.OnConsole(lc.onConsole)
+	context.OnWebError(lc.onWebError)
+	context.OnResponse(lc.onResponse)
+	context.OnRequestFailed(lc.onRequestFailed)
+	for _, page := range context.Pages() {
+		page.OnCrash(lc.onCrash)
+	}
+	context.OnPage(func(page playwright.Page) {
+		page.OnCrash(lc.onCrash)
+	})
+}
+
+func (lc *browserLogCollector) onConsole(message playwright.ConsoleMessage) {
+	switch message.Type() {
+	case "error":
+		lc.add(message.Page(), LogConsoleError, message.Text())
+	case "warning":
+		lc.add(message.Page(), LogConsoleWarning, message.Text())
+	}
+}
+
+func (lc *browserLogCollector) onWebError(webError playwright.WebError) {
+	if webError.Error() == nil {
+		return
+	}
+	lc.add(webError.Page(), LogPageError, webError.Error().Error())
+}
+
+func (lc *browserLogCollector) onResponse(response playwright.Response) {
+	if response.Status() < 400 || isIgnoredLogUrl(response.URL()) {
+		return
+	}
+	request := response.Request()
+	lc.add(requestPage(request), LogHttpError, fmt.Sprintf("%s %s %d %s", request.Method(), response.URL(), response.Status(), response.StatusText()))
+}
+
+func (lc *browserLogCollector) onRequestFailed(request playwright.Request) {
+	failure := request.Failure()
+	// aborted requests are cancelled by the page itself, e.g. by a new navigation
+	if failure == nil || strings.Contains(failure.Error(), "ERR_ABORTED") || isIgnoredLogUrl(request.URL()) {
+		return
+	}
+	lc.add(requestPage(request), LogRequestFailed, fmt.Sprintf("%s %s %s", request.Method(), request.URL(), failure.Error()))
+}
+
+func (lc *browserLogCollector) onCrash(page playwright.Page) {
+	log.Warnf("💥  Page crashed: %s", page.URL())
+	lc.add(page, LogCrash, "page crashed")
+}
+
+// Failures of these requests do not affect the page
+func isIgnoredLogUrl(url string) bool {
+	return strings.HasSuffix(strings.SplitN(url, "?", 2)[0], "/favicon.ico")
+}
+
+func (lc *browserLogCollector) add(page playwright.Page, logType BrowserLogType, message string) {
+	if page == nil {
+		return
+	}
+	if len(message) > MAX_BROWSER_LOG_LENGTH {
+		message = message[:MAX_BROWSER_LOG_LENGTH] + "..."
+	}
+	url := page.URL()
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	pl, ok := lc.pages[page]
+	if !ok {
+		pl = &pageLog{index: make(map[string]*BrowserLogEntry)}
+		lc.pages[page] = pl
+		lc.order = append(lc.order, page)
+	}
+	key := string(logType) + "\x00" + url + "\x00" + message
+	if entry, ok := pl.index[key]; ok {
+		entry.Count++
+		return
+	}
+	if len(pl.entries) >= MAX_BROWSER_LOGS_PER_PAGE {
+		pl.dropped++
+		return
+	}
+	entry := &BrowserLogEntry{Type: logType, Message: message, Url: url, Count: 1, Time: time.Now()}
+	pl.entries = append(pl.entries, entry)
+	pl.index[key] = entry
+}
+
+// Take the entries collected since the last call, page by page
+func (lc *browserLogCollector) take() []*BrowserLogEntry {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	entries := []*BrowserLogEntry{}
+	for _, page := range lc.order {
+		pl := lc.pages[page]
+		entries = append(entries, pl.entries...)
+		if pl.dropped > 0 {
+			log.Debugf("🪨  Dropped %d browser log entries of %s", pl.dropped, page.URL())
+		}
+	}
+	lc.pages = make(map[playwright.Page]*pageLog)
+	lc.order = nil
+	return entries
+}
+
+// Copies of the entries collected since the last take, which are kept for the next take
+func (lc *browserLogCollector) peek() []*BrowserLogEntry {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	entries := []*BrowserLogEntry{}
+	for _, page := range lc.order {
+		for _, entry := range lc.pages[page].entries {
+			copied := *entry
+			entries = append(entries, &copied)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,36 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+type fakePage struct {
+	playwright.Page
+	url string
+}
+
+func (p *fakePage) URL() string { return p.url }
+
+func TestBrowserLogCollectorPeekKeepsEntries(t *testing.T) {
+	lc := newBrowserLogCollector()
+	page := &fakePage{url: "https://example.com/"}
+	lc.add(page, LogConsoleError, "save failed")
+
+	peeked := lc.peek()
+	if len(peeked) != 1 || peeked[0].Message != "save failed" {
+		t.Fatalf("expected the collected entry, got %v", peeked)
+	}
+	peeked[0].Count = 10
+
+	lc.add(page, LogConsoleError, "save failed")
+	lc.add(page, LogPageError, "boom")
+	taken := lc.take()
+	if len(taken) != 2 || taken[0].Count != 2 || taken[1].Message != "boom" {
+		t.Fatalf("expected peek to keep the entries for take, got %v", taken)
+	}
+	if entries := lc.take(); len(entries) != 0 {
+		t.Errorf("expected take to consume the entries, got %v", entries)
+	}
+}
@@ -1,12 +1,22 @@
 package browser
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/nerdface-ai/browser-use-go/internals/dom"
+	"github.com/playwright-community/playwright-go"
 )
 
 func TestNewBrowser(t *testing.T) {
@@ -150,3 +160,893 @@ This domain is for use in illustrative examples in documents. You may use this
 		t.Error("expected", expected, "got", elementStr)
 	}
 }
+
+func TestBrowserEngines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><input id="name" type="text"><button onclick="document.title='clicked'">OK</button></body></html>`))
+	}))
+	defer server.Close()
+
+	for _, browserClass := range []string{BrowserClassChromium, BrowserClassFirefox, BrowserClassWebkit} {
+		t.Run(browserClass, func(t *testing.T) {
+			browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true), WithBrowserClass(browserClass)))
+			defer browser.Close()
+			bc := browser.NewContext()
+			defer bc.Close()
+
+			if err := bc.NavigateTo(server.URL); err != nil {
+				t.Fatal(err)
+			}
+			state := bc.GetState(false)
+			if len(*state.SelectorMap) != 2 {
+				t.Fatalf("expected 2 interactive elements, got %d", len(*state.SelectorMap))
+			}
+			if err := bc.InputTextElementNode((*state.SelectorMap)[0], "Golang"); err != nil {
+				t.Error(err)
+			}
+			if _, err := bc.ClickElementNode((*state.SelectorMap)[1]); err != nil {
+				t.Error(err)
+			}
+			title, _ := bc.GetCurrentPage().Title()
+			if title != "clicked" {
+				t.Errorf("expected title to be clicked, got %q", title)
+			}
+			if _, err := bc.TakeScreenshot(false); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestStartDriverNotInstalled(t *testing.T) {
+	t.Setenv("PLAYWRIGHT_DRIVER_PATH", t.TempDir())
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	err := browser.Start(context.Background())
+	if !errors.Is(err, ErrDriverNotInstalled) {
+		t.Fatalf("expected ErrDriverNotInstalled, got %v", err)
+	}
+
+	bc := browser.NewContext()
+	if _, err := bc.Start(context.Background()); !errors.Is(err, ErrDriverNotInstalled) {
+		t.Errorf("expected ErrDriverNotInstalled from context, got %v", err)
+	}
+	if bc.Session != nil {
+		t.Error("session should not be set when the browser fails to start")
+	}
+}
+
+func TestStartConcurrently(t *testing.T) {
+	t.Setenv("PLAYWRIGHT_DRIVER_PATH", t.TempDir())
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- browser.Start(context.Background())
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; !errors.Is(err, ErrDriverNotInstalled) {
+			t.Errorf("expected ErrDriverNotInstalled, got %v", err)
+		}
+	}
+	if browser.Playwright != nil {
+		t.Error("expected no driver to be left running")
+	}
+}
+
+func TestDriverError(t *testing.T) {
+	err := driverError(errors.New("please install the driver (v1.51.0) first: driver not found"))
+	if !errors.Is(err, ErrDriverNotInstalled) {
+		t.Errorf("expected ErrDriverNotInstalled, got %v", err)
+	}
+	err = driverError(errors.New("could not get driver instance: could not get default cache directory"))
+	if errors.Is(err, ErrDriverNotInstalled) || !errors.Is(err, ErrBrowserLaunch) {
+		t.Errorf("expected ErrBrowserLaunch only, got %v", err)
+	}
+}
+
+func TestWaitForDevToolsActivePort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DEVTOOLS_ACTIVE_PORT_FILE)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.WriteFile(path, []byte("41234\n/devtools/browser/abc\n"), 0644)
+	}()
+	port, err := waitForDevToolsActivePort(context.Background(), path, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 41234 {
+		t.Errorf("expected port 41234, got %d", port)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := waitForDevToolsActivePort(ctx, filepath.Join(t.TempDir(), DEVTOOLS_ACTIVE_PORT_FILE), 5*time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestUserProvidedBrowserPortInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+	if isPortFree(port) {
+		t.Fatalf("port %d should be in use", port)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithBrowserBinaryPath(binary), WithRemoteDebuggingPort(port)))
+	_, err = browser.setupUserProvidedBrowser(context.Background(), nil)
+	if !errors.Is(err, ErrBrowserLaunch) || !strings.Contains(err.Error(), "attach_existing") {
+		t.Errorf("expected port in use error, got %v", err)
+	}
+}
+
+func TestIsRelevantRequest(t *testing.T) {
+	tests := []struct {
+		url          string
+		resourceType string
+		headers      map[string]string
+		expected     bool
+	}{
+		{"https://example.com/", "document", nil, true},
+		{"https://example.com/api/items", "fetch", nil, true},
+		{"https://example.com/video.mp4", "media", nil, false},
+		{"wss://example.com/socket", "websocket", nil, false},
+		{"https://www.google-analytics.com/collect", "script", nil, false},
+		{"https://example.com/events", "fetch", map[string]string{"accept": "text/event-stream"}, false},
+		{"https://example.com/next", "document", map[string]string{"sec-purpose": "prefetch"}, false},
+		{"data:image/png;base64,AAAA", "image", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isRelevantRequest(tt.url, tt.resourceType, tt.headers); got != tt.expected {
+			t.Errorf("isRelevantRequest(%q, %q) = %v, expected %v", tt.url, tt.resourceType, got, tt.expected)
+		}
+	}
+}
+
+func TestWaitForStableNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(1 * time.Second)
+			w.Write([]byte("loaded"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><script>
+			fetch("/slow").then(r => r.text()).then(text => {
+				const button = document.createElement("button");
+				button.textContent = text;
+				document.body.appendChild(button);
+			});
+		</script></body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	bc := browser.NewContext()
+	defer bc.Close()
+
+	page := bc.GetCurrentPage()
+	if _, err := page.Goto(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	state := bc.GetState(false)
+	if len(*state.SelectorMap) != 1 {
+		t.Errorf("expected the button loaded by fetch, got %d elements", len(*state.SelectorMap))
+	}
+	if bc.PageLoadWaitTime() < 500*time.Millisecond {
+		t.Errorf("expected to wait for the slow request, waited %s", bc.PageLoadWaitTime())
+	}
+}
+
+func TestAdaptiveWaits(t *testing.T) {
+	bc := NewBrowserWithSettings(nil).NewContext(WithWaitBetweenActions(0.2), WithClickTimeout(2))
+	bc.recordPageLoad(3 * time.Second)
+	if bc.WaitBetweenActions() != 200*time.Millisecond || *bc.timeoutMs(bc.Config.ClickTimeout) != 2000 {
+		t.Errorf("waits should not adapt unless enabled, got %s and %gms", bc.WaitBetweenActions(), *bc.timeoutMs(bc.Config.ClickTimeout))
+	}
+
+	bc.Config.AdaptiveWaits = true
+	if bc.WaitBetweenActions() != 600*time.Millisecond || *bc.timeoutMs(bc.Config.ClickTimeout) != 6000 {
+		t.Errorf("expected waits scaled by 3, got %s and %gms", bc.WaitBetweenActions(), *bc.timeoutMs(bc.Config.ClickTimeout))
+	}
+
+	for i := 0; i < 20; i++ {
+		bc.recordPageLoad(100 * time.Millisecond)
+	}
+	if bc.WaitBetweenActions() != 200*time.Millisecond {
+		t.Errorf("expected configured wait on fast pages, got %s", bc.WaitBetweenActions())
+	}
+
+	bc.recordPageLoad(time.Minute)
+	if factor := bc.timingFactor(); factor != ADAPTIVE_MAX_FACTOR {
+		t.Errorf("expected factor capped at %g, got %g", ADAPTIVE_MAX_FACTOR, factor)
+	}
+}
+
+func TestUniqueFilename(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "report.pdf"), []byte("pdf"), 0644)
+	reserved := map[string]struct{}{"report (1).pdf": {}}
+	if name := uniqueFilename(dir, "report.pdf", reserved); name != "report (2).pdf" {
+		t.Errorf("expected report (2).pdf, got %s", name)
+	}
+	if name := uniqueFilename(dir, "../escape.txt", nil); name != "escape.txt" {
+		t.Errorf("expected the file name without directories, got %s", name)
+	}
+}
+
+func TestDownloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/report.csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+			w.Write([]byte("a,b\n1,2\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/report.csv">Download</a></body></html>`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	bc := browser.NewContext(WithSaveDownloadsPath(dir))
+	defer bc.Close()
+
+	if err := bc.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	state := bc.GetState(false)
+	path, err := bc.ClickElementNode((*state.SelectorMap)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path == nil || *path != filepath.Join(dir, "report.csv") {
+		t.Fatalf("expected the download path, got %v", path)
+	}
+
+	downloads := bc.GetState(false).Downloads
+	if len(downloads) != 1 {
+		t.Fatalf("expected 1 download, got %d", len(downloads))
+	}
+	if downloads[0].Status != DownloadCompleted || downloads[0].Size != 8 || !strings.HasPrefix(downloads[0].MimeType, "text/csv") {
+		t.Errorf("unexpected download: %+v", downloads[0])
+	}
+}
+
+func TestDialogPolicies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<button onclick="document.title = confirm('Submit?') ? 'confirmed' : 'cancelled'">Confirm</button>
+			<button onclick="document.title = prompt('Name?', 'nobody')">Prompt</button>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+
+	tests := []struct {
+		name          string
+		opts          []ContextOption
+		handler       DialogHandler
+		confirmTitle  string
+		promptTitle   string
+		confirmString string
+	}{
+		{"accept", []ContextOption{WithDialogPromptText("Gopher")}, nil, "confirmed", "Gopher", "was accepted"},
+		{"dismiss", []ContextOption{WithDialogPolicy(DialogPolicyDismiss)}, nil, "cancelled", "null", "was dismissed"},
+		{"ask", []ContextOption{WithDialogPolicy(DialogPolicyAsk)}, func(dialog DialogInfo) DialogResponse {
+			return DialogResponse{Accept: dialog.Type == "prompt", PromptText: "Asked"}
+		}, "cancelled", "Asked", "was dismissed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := browser.NewContext(tt.opts...)
+			defer bc.Close()
+			if tt.handler != nil {
+				bc.SetDialogHandler(tt.handler)
+			}
+			if err := bc.NavigateTo(server.URL); err != nil {
+				t.Fatal(err)
+			}
+			state := bc.GetState(false)
+			page := bc.GetCurrentPage()
+
+			bc.ClickElementNode((*state.SelectorMap)[0])
+			if title, _ := page.Title(); title != tt.confirmTitle {
+				t.Errorf("expected title %q after confirm, got %q", tt.confirmTitle, title)
+			}
+			bc.ClickElementNode((*state.SelectorMap)[1])
+			if title, _ := page.Title(); title != tt.promptTitle {
+				t.Errorf("expected title %q after prompt, got %q", tt.promptTitle, title)
+			}
+
+			dialogs := bc.GetState(false).Dialogs
+			if len(dialogs) != 2 || dialogs[0].Type != "confirm" || dialogs[1].Type != "prompt" {
+				t.Fatalf("expected the confirm and prompt dialogs, got %v", dialogs)
+			}
+			if !strings.Contains(dialogs[0].String(), tt.confirmString) {
+				t.Errorf("expected %q in %q", tt.confirmString, dialogs[0].String())
+			}
+			if len(bc.GetState(false).Dialogs) != 0 {
+				t.Error("dialogs should only be reported once")
+			}
+		})
+	}
+}
+
+func TestBrowserLogsDigest(t *testing.T) {
+	entries := []*BrowserLogEntry{{Type: LogConsoleWarning, Message: "deprecated api", Count: 1}}
+	for i := 0; i < MAX_BROWSER_ERRORS_IN_STATE+1; i++ {
+		entries = append(entries, &BrowserLogEntry{Type: LogHttpError, Message: fmt.Sprintf("GET /api/%d 500 Internal Server Error", i), Count: 2})
+	}
+	digest := BrowserLogsDigest(entries)
+	if len(digest) != MAX_BROWSER_ERRORS_IN_STATE+1 {
+		t.Fatalf("expected %d lines, got %v", MAX_BROWSER_ERRORS_IN_STATE+1, digest)
+	}
+	if digest[0] != "[http_error] GET /api/0 500 Internal Server Error (x2)" {
+		t.Errorf("errors should come first, got %q", digest[0])
+	}
+	if digest[len(digest)-1] != "... and 2 more" {
+		t.Errorf("expected the number of omitted entries, got %q", digest[len(digest)-1])
+	}
+	if len(BrowserLogsDigest(entries[:1])) != 1 || len(BrowserLogsDigest(nil)) != 0 {
+		t.Error("short lists should be kept as they are")
+	}
+}
+
+func TestBrowserLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><script>
+			for (let i = 0; i < 3; i++) console.error("boom");
+			console.warn("careful");
+			setTimeout(() => { throw new Error("kaput") });
+			fetch("/api");
+		</script></body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	bc := browser.NewContext()
+	defer bc.Close()
+
+	if err := bc.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	state := bc.GetState(false)
+	digest := strings.Join(state.BrowserErrors, "\n")
+	for _, expected := range []string{
+		"[console_error] boom (x3)",
+		"[console_warning] careful",
+		"[page_error] ",
+		"kaput",
+		fmt.Sprintf("[http_error] GET %s/api 500 Internal Server Error", server.URL),
+	} {
+		if !strings.Contains(digest, expected) {
+			t.Errorf("expected %q in %v", expected, state.BrowserErrors)
+		}
+	}
+	if len(state.BrowserLogs) < 4 || state.BrowserLogs[0].Url != server.URL+"/" {
+		t.Errorf("unexpected browser logs: %v", state.BrowserLogs)
+	}
+	if len(bc.GetState(false).BrowserErrors) != 0 {
+		t.Error("browser logs should only be reported once")
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		url   string
+		match bool
+	}{
+		{"**/*.png", "https://example.com/img/logo.png", true},
+		{"**/*.png", "https://example.com/img/logo.png?v=2", false},
+		{"https://example.com/*", "https://example.com/index.html", true},
+		{"https://example.com/*", "https://example.com/a/b.html", false},
+		{"**/api/**", "http://localhost:8080/api/v1/users", true},
+		{"**/*.{woff,woff2}", "https://cdn.example.com/font.woff2", true},
+		{"**/*.{woff,woff2}", "https://cdn.example.com/font.ttf", false},
+		{"https://example.com/a+b", "https://example.com/a+b", true},
+	}
+	for _, tt := range tests {
+		pattern, err := globToRegexp(tt.glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pattern.MatchString(tt.url) != tt.match {
+			t.Errorf("glob %q on %q: expected match %v", tt.glob, tt.url, tt.match)
+		}
+	}
+	if _, err := globToRegexp("**/*.{png,jpg"); err == nil {
+		t.Error("expected an error for an unclosed group")
+	}
+}
+
+func TestLoadRouteRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.json")
+	os.WriteFile(path, []byte(`[
+		{"resource_types": ["image", "tracker"], "action": "block"},
+		{"url": "**/api/user", "action": "mock", "file": "user.json"}
+	]`), 0644)
+
+	rules, err := LoadRouteRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Action != RouteActionBlock || rules[1].File != filepath.Join(dir, "user.json") {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+}
+
+func TestRouteRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/user":
+			w.Write([]byte(`{"name": "backend"}`))
+		case "/whoami":
+			w.Write([]byte(r.Header.Get("X-Agent")))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><img src="/logo.png"></body></html>`))
+		}
+	}))
+	defer server.Close()
+	mockFile := filepath.Join(t.TempDir(), "user.json")
+	os.WriteFile(mockFile, []byte(`{"name": "mocked"}`), 0644)
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	bc := browser.NewContext(WithRouteRules(
+		RouteRule{ResourceTypes: []string{"image"}, Action: RouteActionBlock},
+		RouteRule{Url: "**/api/user", Action: RouteActionMock, File: mockFile},
+		RouteRule{Url: server.URL + "/**", Action: RouteActionHeaders, Headers: map[string]string{"X-Agent": "browser-use"}},
+	))
+	defer bc.Close()
+
+	if err := bc.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	page := bc.GetCurrentPage()
+	fetchText := func(path string) string {
+		text, err := page.Evaluate(`path => fetch(path).then(r => r.text()).catch(() => "failed")`, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return text.(string)
+	}
+	if text := fetchText("/api/user"); text != `{"name": "mocked"}` {
+		t.Errorf("expected the mocked response, got %q", text)
+	}
+	if text := fetchText("/whoami"); text != "browser-use" {
+		t.Errorf("expected the added header, got %q", text)
+	}
+	if loaded, _ := page.Evaluate(`() => document.querySelector("img").complete && document.querySelector("img").naturalWidth > 0`); loaded == true {
+		t.Error("expected the image to be blocked")
+	}
+
+	if err := bc.SetRouteRules([]RouteRule{{Url: "**/api/**", Action: RouteActionBlock}}); err != nil {
+		t.Fatal(err)
+	}
+	if text := fetchText("/api/user"); text != "failed" {
+		t.Errorf("expected the request to be blocked after reloading the rules, got %q", text)
+	}
+
+	missingFile := filepath.Join(t.TempDir(), "missing.json")
+	if err := bc.SetRouteRules([]RouteRule{{Url: "**/api/user", Action: RouteActionMock, File: missingFile}}); err == nil {
+		t.Error("expected an error for a missing mock file")
+	}
+	if err := bc.SetRouteRules([]RouteRule{{Url: "**/api/user", Action: RouteActionMock, File: mockFile}}); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(mockFile)
+	if text := fetchText("/api/user"); text != "failed" {
+		t.Errorf("expected the request to be aborted when the mock file is gone, got %q", text)
+	}
+	if err := bc.SetRouteRules(nil); err != nil {
+		t.Fatal(err)
+	}
+	if text := fetchText("/api/user"); text != `{"name": "backend"}` {
+		t.Errorf("expected the backend response without rules, got %q", text)
+	}
+}
+
+func TestStartFailedSetupLeavesNoSession(t *testing.T) {
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	if err := browser.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	bc := browser.NewContext(WithRouteRulesFile(filepath.Join(t.TempDir(), "missing.json")))
+	if _, err := bc.Start(context.Background()); err == nil {
+		t.Fatal("expected a missing route_rules_file to fail Start")
+	}
+	if bc.Session != nil || bc.network != nil || bc.browserLogs != nil {
+		t.Error("expected no session and no trackers after a failed setup")
+	}
+	if contexts := browser.PlaywrightBrowser.Contexts(); len(contexts) != 0 {
+		t.Errorf("expected the context of the failed setup to be closed, got %d contexts", len(contexts))
+	}
+
+	bc.Config.RouteRulesFile = ""
+	defer bc.Close()
+	if _, err := bc.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateContextFailedSetupClosesContext(t *testing.T) {
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	if err := browser.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	bc := browser.NewContext(WithReplayHar(filepath.Join(t.TempDir(), "missing.har"), HarNotFoundAbort))
+	if _, err := bc.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "replay_har_path") {
+		t.Fatalf("expected a missing replay_har_path to fail Start, got %v", err)
+	}
+	if contexts := browser.PlaywrightBrowser.Contexts(); len(contexts) != 0 {
+		t.Errorf("expected the context created for the failed setup to be closed, got %d contexts", len(contexts))
+	}
+	if len(bc.HarPaths()) != 0 {
+		t.Errorf("expected no HAR of the failed setup, got %v", bc.HarPaths())
+	}
+}
+
+func TestHarRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Recorded</title></head><body>recorded page</body></html>`))
+	}))
+	url := server.URL + "/page"
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+
+	dir := t.TempDir()
+	bc := browser.NewContext(WithSaveHarPath(dir))
+	if err := bc.NavigateTo(url); err != nil {
+		t.Fatal(err)
+	}
+	bc.Close()
+	server.Close()
+
+	harPaths := bc.HarPaths()
+	if len(harPaths) != 1 || filepath.Dir(harPaths[0]) != dir {
+		t.Fatalf("expected one HAR in %s, got %v", dir, harPaths)
+	}
+	if stat, err := os.Stat(harPaths[0]); err != nil || stat.Size() == 0 {
+		t.Fatalf("expected the HAR to be written on close: %v", err)
+	}
+
+	replay := browser.NewContext(WithReplayHar(harPaths[0], HarNotFoundAbort))
+	defer replay.Close()
+	if err := replay.NavigateTo(url); err != nil {
+		t.Fatal(err)
+	}
+	if title, _ := replay.GetCurrentPage().Title(); title != "Recorded" {
+		t.Errorf("expected the page to be served from the HAR, got title %q", title)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>traced</body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+
+	t.Run("session", func(t *testing.T) {
+		dir := t.TempDir()
+		bc := browser.NewContext(WithTracePath(dir))
+		if err := bc.NavigateTo(server.URL); err != nil {
+			t.Fatal(err)
+		}
+		bc.Close()
+		if paths := bc.TracePaths(); len(paths) != 1 || paths[0] != filepath.Join(dir, "trace.zip") {
+			t.Fatalf("expected trace.zip, got %v", paths)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "trace.zip")); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("chunk per step", func(t *testing.T) {
+		dir := t.TempDir()
+		bc := browser.NewContext(WithTracePath(dir), WithTraceChunkPerStep(true))
+		if err := bc.NavigateTo(server.URL); err != nil {
+			t.Fatal(err)
+		}
+		for _, step := range []string{"step_1", "step_2"} {
+			if err := bc.NextTraceChunk(step); err != nil {
+				t.Fatal(err)
+			}
+			bc.GetState(false)
+		}
+		bc.Close()
+		var names []string
+		for _, path := range bc.TracePaths() {
+			if _, err := os.Stat(path); err != nil {
+				t.Error(err)
+			}
+			names = append(names, filepath.Base(path))
+		}
+		if strings.Join(names, ",") != "start.zip,step_1.zip,step_2.zip" {
+			t.Errorf("expected one trace per chunk, got %v", names)
+		}
+	})
+}
+
+func TestRecordVideo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/" target="_blank">new tab</a></body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+
+	dir := t.TempDir()
+	bc := browser.NewContext(WithRecordVideo(dir, &playwright.Size{Width: 320, Height: 240}))
+	bc.SetVideoName("agent-1")
+	if err := bc.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.CreateNewTab(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	// the name is fixed for the running session
+	bc.SetVideoName("agent-2")
+	bc.Close()
+
+	expected := []string{filepath.Join(dir, "agent-1_tab0.webm"), filepath.Join(dir, "agent-1_tab1.webm")}
+	if strings.Join(bc.VideoPaths(), ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, bc.VideoPaths())
+	}
+	for _, path := range expected {
+		if stat, err := os.Stat(path); err != nil || stat.Size() == 0 {
+			t.Errorf("expected a video at %s: %v", path, err)
+		}
+	}
+
+	// the next session uses the name set since
+	if err := bc.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	bc.Close()
+	if paths := bc.VideoPaths(); len(paths) != 3 || paths[2] != filepath.Join(dir, "agent-2_tab0.webm") {
+		t.Errorf("expected the video of the next session named agent-2, got %v", paths)
+	}
+}
+
+func TestStorageState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>storage</body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	bc := browser.NewContext(WithStorageStateFile(path))
+	if err := bc.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	_, err := bc.GetCurrentPage().Evaluate(`() => {
+		document.cookie = "session=cookie-token";
+		localStorage.setItem("token", "local-token");
+		sessionStorage.setItem("token", "session-token");
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.Close()
+
+	var state StorageState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Cookies) != 1 || len(state.Origins) != 1 || len(state.SessionStorage) != 1 {
+		t.Fatalf("expected the cookie, localStorage and sessionStorage to be saved, got %s", data)
+	}
+
+	restored := browser.NewContext(WithStorageStateFile(path))
+	defer restored.Close()
+	if err := restored.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	values, err := restored.GetCurrentPage().Evaluate(`() => [document.cookie, localStorage.getItem("token"), sessionStorage.getItem("token")]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(values) != "[session=cookie-token local-token session-token]" {
+		t.Errorf("expected the storage to be restored, got %v", values)
+	}
+}
+
+func TestPersistentProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>profile</body></html>`))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	for i, expected := range []string{"<nil>", "logged-in"} {
+		browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true), WithProfile(dir, "customer-a")))
+		bc := browser.NewContext()
+		if err := bc.NavigateTo(server.URL); err != nil {
+			t.Fatal(err)
+		}
+		value, err := bc.GetCurrentPage().Evaluate(`() => { const value = localStorage.getItem("token"); localStorage.setItem("token", "logged-in"); return value }`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(value) != expected {
+			t.Errorf("run %d: expected %s, got %v", i, expected, value)
+		}
+
+		second := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true), WithProfile(dir, "customer-a")))
+		if err := second.Start(context.Background()); !errors.Is(err, ErrProfileLocked) {
+			t.Errorf("expected the profile to be locked while the browser runs, got %v", err)
+		}
+		bc.Close()
+		browser.Close()
+	}
+}
+
+func TestContextEmulation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>` + r.Header.Get("X-Region") + `</body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+	bc := browser.NewContext(
+		WithTimezoneId("Europe/Berlin"),
+		WithGeolocation(52.52, 13.405),
+		WithColorScheme(ColorSchemeDark),
+		WithReducedMotion(ReducedMotionReduce),
+		WithExtraHttpHeaders(map[string]string{"X-Region": "eu"}),
+		WithDeviceScaleFactor(2),
+	)
+	defer bc.Close()
+	if err := bc.NavigateTo(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	result, err := bc.GetCurrentPage().Evaluate(`async () => {
+		const position = await new Promise((resolve, reject) => navigator.geolocation.getCurrentPosition(resolve, reject));
+		return [
+			document.body.textContent,
+			Intl.DateTimeFormat().resolvedOptions().timeZone,
+			String(position.coords.latitude),
+			String(matchMedia("(prefers-color-scheme: dark)").matches),
+			String(matchMedia("(prefers-reduced-motion: reduce)").matches),
+			String(devicePixelRatio),
+		].join(" ");
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "eu Europe/Berlin 52.52 true true 2" {
+		t.Errorf("emulation not applied, got %v", result)
+	}
+
+	offline := browser.NewContext(WithOffline(true))
+	defer offline.Close()
+	if online, _ := offline.GetCurrentPage().Evaluate(`() => navigator.onLine`); online != false {
+		t.Errorf("expected the context to be offline, got navigator.onLine %v", online)
+	}
+}
+
+func TestDeviceEmulation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="viewport" content="width=device-width"></head><body>device</body></html>`))
+	}))
+	defer server.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true)))
+	defer browser.Close()
+
+	tests := []struct {
+		name     string
+		opts     []ContextOption
+		expected ViewportInfo
+	}{
+		{"device", []ContextOption{WithDevice("Pixel 7")}, ViewportInfo{Width: 412, Height: 839, DeviceScaleFactor: 2.625, IsMobile: true}},
+		{"viewport overrides device", []ContextOption{WithDevice("Pixel 7"), WithViewport(360, 640)}, ViewportInfo{Width: 360, Height: 640, DeviceScaleFactor: 2.625, IsMobile: true}},
+		{"viewport", []ContextOption{WithViewport(1024, 768)}, ViewportInfo{Width: 1024, Height: 768, DeviceScaleFactor: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := browser.NewContext(tt.opts...)
+			defer bc.Close()
+			if err := bc.NavigateTo(server.URL); err != nil {
+				t.Fatal(err)
+			}
+			state := bc.GetState(false)
+			if state.Viewport == nil || *state.Viewport != tt.expected {
+				t.Errorf("expected viewport %+v, got %+v", tt.expected, state.Viewport)
+			}
+		})
+	}
+
+	bc := browser.NewContext(WithDevice("iPhone 99"))
+	defer bc.Close()
+	if _, err := bc.initializeSession(); err == nil || !strings.Contains(err.Error(), `unknown device "iPhone 99"`) {
+		t.Errorf("expected unknown device error, got %v", err)
+	}
+}
+
+func TestContextProxies(t *testing.T) {
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>` + name + ` ` + r.Host + `</body></html>`))
+		}))
+	}
+	proxyA, proxyB := newProxy("proxy-a"), newProxy("proxy-b")
+	defer proxyA.Close()
+	defer proxyB.Close()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true), WithProxyPool(
+		playwright.Proxy{Server: proxyA.URL},
+		playwright.Proxy{Server: proxyB.URL},
+	)))
+	defer browser.Close()
+
+	for _, expected := range []string{"proxy-a", "proxy-b", "proxy-a"} {
+		bc := browser.NewContext()
+		if err := bc.NavigateTo("http://browser-use.test/"); err != nil {
+			t.Fatal(err)
+		}
+		text, err := bc.GetCurrentPage().TextContent("body")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != expected+" browser-use.test" {
+			t.Errorf("expected the page to be served by %s, got %q", expected, text)
+		}
+		if !strings.HasPrefix(bc.ProxyServer(), "http://127.0.0.1:") {
+			t.Errorf("expected the proxy server of the context, got %q", bc.ProxyServer())
+		}
+		bc.Close()
+	}
+}
@@ -0,0 +1,625 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Supported values of BrowserSettings.BrowserClass
+const (
+	BrowserClassChromium = "chromium"
+	BrowserClassFirefox  = "firefox"
+	BrowserClassWebkit   = "webkit"
+)
+
+// Configuration of a browser context.
+// The map key accepted by BrowserConfigFromMap/ContextConfigFromMap is noted next to each field.
+type BrowserContextConfig struct {
+	CookiesFile       string   // "cookies_file": load cookies from and save them to this file
+	StorageStateFile  string   // "storage_state_file": load cookies, localStorage and sessionStorage from this file and save them to it on close
+	KeepAlive         bool     // "keep_alive": do not close the playwright context when the BrowserContext is closed
+	SaveDownloadsPath string   // "save_downloads_path": directory for files downloaded by any page, a temporary directory removed on close if empty
+	AllowedDomains    []string // "allowed_domains": navigation is restricted to these domains and their subdomains if not empty
+
+	HighlightElements        bool // "highlight_elements": highlight interactive elements on the page
+	ViewportExpansion        int  // "viewport_expansion": pixels around the viewport to include elements from, -1 for the whole page
+	IncludeDynamicAttributes bool // "include_dynamic_attributes": include dynamic attributes in css selectors
+
+	MinimumWaitPageLoadTime        float64 // "minimum_wait_page_load_time": seconds to wait at least before capturing the page state
+	WaitForNetworkIdlePageLoadTime float64 // "wait_for_network_idle_page_load_time": seconds without network activity before the page counts as loaded
+	MaximumWaitPageLoadTime        float64 // "maximum_wait_page_load_time": seconds to wait at most for the network to become idle
+	WaitBetweenActions             float64 // "wait_between_actions": seconds to wait between the actions of a step
+	ClickTimeout                   float64 // "click_timeout": seconds to wait for an element to be clickable and for a new tab after a click
+	InputTimeout                   float64 // "input_timeout": seconds to wait for an element to become visible before input
+	AdaptiveWaits                  bool    // "adaptive_waits": scale wait_between_actions and the timeouts up to 4x on pages that load slowly
+
+	DialogPolicy     string // "dialog_policy": how to handle javascript dialogs, one of accept, dismiss, ask
+	DialogPromptText string // "dialog_prompt_text": text to answer prompt dialogs with for the accept policy, the default value of the prompt if empty
+
+	RouteRules     []RouteRule // "route_rules": list of maps with the json keys of RouteRule, to block, mock or rewrite requests
+	RouteRulesFile string      // "route_rules_file": JSON file with more route rules, applied after route_rules and reloadable with ReloadRouteRules
+
+	SaveHarPath       string // "save_har_path": directory to record a HAR file per session to, written when the context is closed
+	ReplayHarPath     string // "replay_har_path": serve all requests from this HAR file instead of the network
+	ReplayHarNotFound string // "replay_har_not_found": what to do with requests missing from replay_har_path, one of abort, fallback
+
+	TracePath         string // "trace_path": directory to save a playwright trace with screenshots, snapshots and sources to, trace.zip per session
+	TraceChunkPerStep bool   // "trace_chunk_per_step": save one trace per agent step, named step_<n>.zip, instead of trace.zip
+
+	StorageStateSaveInterval float64 // "storage_state_save_interval": seconds between saves of storage_state_file while the session is open, 0 to only save on close
+	StorageStateSaveOnLoad   bool    // "storage_state_save_on_load": save storage_state_file whenever a page has loaded
+
+	RecordVideoDir  string           // "record_video_dir": directory to record a video of every page to, named <agent id>_tab<n>.webm
+	RecordVideoSize *playwright.Size // "record_video_size": map with width and height, the viewport scaled down to fit 800x800 if empty
+
+	Proxy *playwright.Proxy // "context_proxy": map with server, bypass, username and password, the proxy of this context instead of the browser's
+
+	Device   string           // "device": name of a playwright device descriptor like "iPhone 13" or "Pixel 7", for its user agent, viewport, screen, scale factor and touch; explicit options take precedence
+	Viewport *playwright.Size // "viewport": map with width and height, pages size to the browser window if empty and no device is set
+
+	UserAgent       string                      // "user_agent"
+	Locale          string                      // "locale"
+	TimezoneId      string                      // "timezone_id"
+	HttpCredentials *playwright.HttpCredentials // "http_credentials"
+	IsMobile        *bool                       // "is_mobile": defaults to the device preset, false without one
+	HasTouch        *bool                       // "has_touch": defaults to the device preset, false without one
+
+	Geolocation       *playwright.Geolocation // "geolocation": map with latitude, longitude and optional accuracy, pages can only read it with the geolocation permission
+	Permissions       []string                // "permissions": permissions granted to all origins, e.g. geolocation, notifications, clipboard-read, clipboard-write
+	ColorScheme       string                  // "color_scheme": one of light, dark, no-preference, the browser default if empty
+	ReducedMotion     string                  // "reduced_motion": one of reduce, no-preference, the browser default if empty
+	ExtraHttpHeaders  map[string]string       // "extra_http_headers": headers sent with every request of the context
+	Offline           bool                    // "offline": emulate a network outage
+	DeviceScaleFactor float64                 // "device_scale_factor": e.g. 2 for a high DPI screen, pages then get playwright's default 1280x720 viewport instead of sizing to the window
+}
+
+// Typed configuration of a browser, see NewBrowserWithSettings.
+// The map key accepted by BrowserConfigFromMap is noted next to each field.
+type BrowserSettings struct {
+	Headless               bool   // "headless"
+	DisableSecurity        bool   // "disable_security": disable browser security features like CORS and CSP
+	DeterministicRendering bool   // "deterministic_rendering": render the same on every machine, slower
+	BrowserClass           string // "browser_class": one of chromium, firefox, webkit
+
+	BrowserBinaryPath string // "browser_binary_path": launch this (chromium based) browser instead of the playwright one
+	CdpUrl            string // "cdp_url": connect to a running browser via CDP
+	WssUrl            string // "wss_url": connect to a running playwright browser server
+
+	UserDataDir         string // "user_data_dir": profile directory, the builtin browser then runs a persistent context; a temporary directory for browser_binary_path if empty
+	ProfileDirectory    string // "profile_directory": profile inside user_data_dir for chromium
+	ProfilesDir         string // "profiles_dir": root directory of the named profiles, see ProfileManager
+	Profile             string // "profile": name of a profile in profiles_dir to use as user_data_dir, created if missing and locked while the browser runs
+	RemoteDebuggingPort int    // "remote_debugging_port": debugging port for browser_binary_path, picked by the browser if 0
+	AttachExisting      bool   // "attach_existing": reuse a browser already listening on remote_debugging_port instead of failing
+	ChromeLogPath       string // "chrome_log_path": file the output of browser_binary_path is appended to, discarded if empty
+
+	ExtraBrowserArgs []string           // "extra_browser_args"
+	Proxy            *playwright.Proxy  // "proxy": map with server, bypass, username and password; chrome of browser_binary_path only takes server and bypass, contexts of a cdp_url or wss_url browser use it as their proxy
+	ProxyPool        []playwright.Proxy // "proxy_pool": list of proxy maps, each new context gets one picked by the proxy selector, round robin by default
+
+	// Configuration for contexts created by Browser.NewContext.
+	// Context keys may be given in the same map as the browser keys.
+	NewContextConfig BrowserContextConfig
+}
+
+// Default context configuration
+func NewContextConfig(opts ...ContextOption) *BrowserContextConfig {
+	config := &BrowserContextConfig{
+		HighlightElements:        true,
+		ViewportExpansion:        0,
+		IncludeDynamicAttributes: true,
+
+		MinimumWaitPageLoadTime:        0.25,
+		WaitForNetworkIdlePageLoadTime: 0.5,
+		MaximumWaitPageLoadTime:        5,
+		WaitBetweenActions:             0.5,
+		ClickTimeout:                   1.5,
+		InputTimeout:                   1,
+
+		DialogPolicy: DialogPolicyAccept,
+
+		ReplayHarNotFound: HarNotFoundAbort,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// Default browser settings
+func NewBrowserSettings(opts ...BrowserOption) *BrowserSettings {
+	config := &BrowserSettings{
+		Headless:         false,
+		DisableSecurity:  false,
+		BrowserClass:     BrowserClassChromium,
+		NewContextConfig: *NewContextConfig(),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// Validate the context configuration
+func (c *BrowserContextConfig) Validate() error {
+	var errs []error
+	if c.ViewportExpansion < -1 {
+		errs = append(errs, fmt.Errorf("viewport_expansion must be -1 or greater, got %d", c.ViewportExpansion))
+	}
+	for _, domain := range c.AllowedDomains {
+		if strings.TrimSpace(domain) == "" {
+			errs = append(errs, errors.New("allowed_domains must not contain empty domains"))
+			break
+		}
+	}
+	if c.MinimumWaitPageLoadTime < 0 || c.WaitForNetworkIdlePageLoadTime < 0 || c.MaximumWaitPageLoadTime < 0 || c.WaitBetweenActions < 0 {
+		errs = append(errs, errors.New("wait times must not be negative"))
+	}
+	if c.ClickTimeout <= 0 || c.InputTimeout <= 0 {
+		errs = append(errs, errors.New("click_timeout and input_timeout must be positive"))
+	}
+	if c.MinimumWaitPageLoadTime > c.MaximumWaitPageLoadTime {
+		errs = append(errs, fmt.Errorf("minimum_wait_page_load_time (%g) must not be greater than maximum_wait_page_load_time (%g)", c.MinimumWaitPageLoadTime, c.MaximumWaitPageLoadTime))
+	}
+	dialogPolicies := []string{DialogPolicyAccept, DialogPolicyDismiss, DialogPolicyAsk}
+	if !slices.Contains(dialogPolicies, c.DialogPolicy) {
+		errs = append(errs, fmt.Errorf("dialog_policy must be one of %s, got %q", strings.Join(dialogPolicies, ", "), c.DialogPolicy))
+	}
+	if _, err := compileRouteRules(c.RouteRules); err != nil {
+		errs = append(errs, err)
+	}
+	if c.SaveHarPath != "" && c.ReplayHarPath != "" {
+		errs = append(errs, errors.New("save_har_path and replay_har_path can not be combined"))
+	}
+	if c.StorageStateSaveInterval < 0 {
+		errs = append(errs, errors.New("storage_state_save_interval must not be negative"))
+	}
+	if (c.StorageStateSaveInterval > 0 || c.StorageStateSaveOnLoad) && c.StorageStateFile == "" {
+		errs = append(errs, errors.New("storage_state_save_interval and storage_state_save_on_load require storage_state_file"))
+	}
+	if c.TraceChunkPerStep && c.TracePath == "" {
+		errs = append(errs, errors.New("trace_chunk_per_step requires trace_path"))
+	}
+	if c.ReplayHarNotFound != HarNotFoundAbort && c.ReplayHarNotFound != HarNotFoundFallback {
+		errs = append(errs, fmt.Errorf("replay_har_not_found must be one of %s, %s, got %q", HarNotFoundAbort, HarNotFoundFallback, c.ReplayHarNotFound))
+	}
+	if c.Proxy != nil && c.Proxy.Server == "" {
+		errs = append(errs, errors.New("context_proxy requires a server"))
+	}
+	if c.Viewport != nil && (c.Viewport.Width <= 0 || c.Viewport.Height <= 0) {
+		errs = append(errs, errors.New("viewport requires a positive width and height"))
+	}
+	if c.RecordVideoSize != nil && (c.RecordVideoSize.Width <= 0 || c.RecordVideoSize.Height <= 0) {
+		errs = append(errs, errors.New("record_video_size requires a positive width and height"))
+	}
+	if c.HttpCredentials != nil && c.HttpCredentials.Username == "" {
+		errs = append(errs, errors.New("http_credentials requires a username"))
+	}
+	errs = append(errs, c.validateEmulation()...)
+	return errors.Join(errs...)
+}
+
+// Validate the browser configuration, including the configuration for new contexts
+func (c *BrowserSettings) Validate() error {
+	var errs []error
+	browserClasses := []string{BrowserClassChromium, BrowserClassFirefox, BrowserClassWebkit}
+	if !slices.Contains(browserClasses, c.BrowserClass) {
+		errs = append(errs, fmt.Errorf("browser_class must be one of %s, got %q", strings.Join(browserClasses, ", "), c.BrowserClass))
+	}
+	if c.CdpUrl != "" && c.WssUrl != "" {
+		errs = append(errs, errors.New("cdp_url and wss_url can not be used together"))
+	}
+	if c.CdpUrl != "" && c.BrowserClass != BrowserClassChromium {
+		errs = append(errs, fmt.Errorf("cdp_url is only supported for chromium, got browser_class %q", c.BrowserClass))
+	}
+	if c.BrowserBinaryPath != "" && c.BrowserClass != BrowserClassChromium {
+		errs = append(errs, errors.New("browser_binary_path only supports chromium browsers (make sure browser_class=chromium)"))
+	}
+	if c.RemoteDebuggingPort < 0 || c.RemoteDebuggingPort > 65535 {
+		errs = append(errs, fmt.Errorf("remote_debugging_port must be between 0 and 65535, got %d", c.RemoteDebuggingPort))
+	}
+	if c.AttachExisting && (c.BrowserBinaryPath == "" || c.RemoteDebuggingPort == 0) {
+		errs = append(errs, errors.New("attach_existing requires browser_binary_path and remote_debugging_port"))
+	}
+	if c.Proxy != nil && c.Proxy.Server == "" {
+		errs = append(errs, errors.New("proxy requires a server"))
+	}
+	for i, proxy := range c.ProxyPool {
+		if proxy.Server == "" {
+			errs = append(errs, fmt.Errorf("proxy_pool[%d] requires a server", i))
+		}
+	}
+	if c.Profile != "" {
+		if c.ProfilesDir == "" {
+			errs = append(errs, errors.New("profile requires profiles_dir"))
+		}
+		if c.UserDataDir != "" {
+			errs = append(errs, errors.New("profile and user_data_dir can not be used together"))
+		}
+		if c.CdpUrl != "" || c.WssUrl != "" {
+			errs = append(errs, errors.New("profile can not be used with cdp_url or wss_url"))
+		}
+		if err := validateProfileName(c.Profile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := c.NewContextConfig.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+type BrowserOption func(*BrowserSettings)
+
+func WithHeadless(headless bool) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.Headless = headless
+	}
+}
+
+func WithDisableSecurity(disableSecurity bool) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.DisableSecurity = disableSecurity
+	}
+}
+
+func WithDeterministicRendering(deterministicRendering bool) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.DeterministicRendering = deterministicRendering
+	}
+}
+
+func WithBrowserClass(browserClass string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.BrowserClass = browserClass
+	}
+}
+
+func WithBrowserBinaryPath(path string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.BrowserBinaryPath = path
+	}
+}
+
+func WithCdpUrl(cdpUrl string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.CdpUrl = cdpUrl
+	}
+}
+
+func WithWssUrl(wssUrl string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.WssUrl = wssUrl
+	}
+}
+
+func WithUserDataDir(userDataDir string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.UserDataDir = userDataDir
+	}
+}
+
+// Use the named profile of a ProfileManager rooted at profilesDir
+func WithProfile(profilesDir string, profile string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.ProfilesDir = profilesDir
+		c.Profile = profile
+	}
+}
+
+func WithProfileDirectory(profileDirectory string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.ProfileDirectory = profileDirectory
+	}
+}
+
+func WithRemoteDebuggingPort(port int) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.RemoteDebuggingPort = port
+	}
+}
+
+// Reuse a browser already listening on the remote debugging port instead of failing to launch a new one
+func WithAttachExisting(attachExisting bool) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.AttachExisting = attachExisting
+	}
+}
+
+func WithChromeLogPath(path string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.ChromeLogPath = path
+	}
+}
+
+func WithExtraBrowserArgs(args ...string) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.ExtraBrowserArgs = append(c.ExtraBrowserArgs, args...)
+	}
+}
+
+func WithProxy(proxy playwright.Proxy) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.Proxy = &proxy
+	}
+}
+
+// Proxies for new contexts, picked by the selector set with Browser.SetProxySelector
+func WithProxyPool(proxies ...playwright.Proxy) BrowserOption {
+	return func(c *BrowserSettings) {
+		c.ProxyPool = proxies
+	}
+}
+
+// Apply context options to the configuration of contexts created by Browser.NewContext
+func WithNewContextConfig(opts ...ContextOption) BrowserOption {
+	return func(c *BrowserSettings) {
+		for _, opt := range opts {
+			opt(&c.NewContextConfig)
+		}
+	}
+}
+
+type ContextOption func(*BrowserContextConfig)
+
+func WithCookiesFile(cookiesFile string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.CookiesFile = cookiesFile
+	}
+}
+
+func WithKeepAlive(keepAlive bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.KeepAlive = keepAlive
+	}
+}
+
+func WithSaveDownloadsPath(path string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.SaveDownloadsPath = path
+	}
+}
+
+func WithAllowedDomains(domains ...string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.AllowedDomains = append(c.AllowedDomains, domains...)
+	}
+}
+
+func WithHighlightElements(highlightElements bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.HighlightElements = highlightElements
+	}
+}
+
+func WithViewportExpansion(viewportExpansion int) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.ViewportExpansion = viewportExpansion
+	}
+}
+
+func WithIncludeDynamicAttributes(includeDynamicAttributes bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.IncludeDynamicAttributes = includeDynamicAttributes
+	}
+}
+
+func WithMinimumWaitPageLoadTime(seconds float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.MinimumWaitPageLoadTime = seconds
+	}
+}
+
+func WithWaitForNetworkIdlePageLoadTime(seconds float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.WaitForNetworkIdlePageLoadTime = seconds
+	}
+}
+
+func WithMaximumWaitPageLoadTime(seconds float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.MaximumWaitPageLoadTime = seconds
+	}
+}
+
+func WithWaitBetweenActions(seconds float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.WaitBetweenActions = seconds
+	}
+}
+
+func WithClickTimeout(seconds float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.ClickTimeout = seconds
+	}
+}
+
+func WithInputTimeout(seconds float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.InputTimeout = seconds
+	}
+}
+
+// Scale the wait between actions and the interaction timeouts with the measured page load speed
+func WithAdaptiveWaits(adaptiveWaits bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.AdaptiveWaits = adaptiveWaits
+	}
+}
+
+func WithDialogPolicy(policy string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.DialogPolicy = policy
+	}
+}
+
+func WithDialogPromptText(text string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.DialogPromptText = text
+	}
+}
+
+func WithRouteRules(rules ...RouteRule) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.RouteRules = rules
+	}
+}
+
+func WithRouteRulesFile(path string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.RouteRulesFile = path
+	}
+}
+
+func WithSaveHarPath(path string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.SaveHarPath = path
+	}
+}
+
+// Serve all requests from a HAR file, notFound is one of abort, fallback
+func WithReplayHar(path string, notFound string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.ReplayHarPath = path
+		c.ReplayHarNotFound = notFound
+	}
+}
+
+func WithTracePath(path string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.TracePath = path
+	}
+}
+
+func WithTraceChunkPerStep(chunkPerStep bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.TraceChunkPerStep = chunkPerStep
+	}
+}
+
+func WithRecordVideo(dir string, size *playwright.Size) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.RecordVideoDir = dir
+		c.RecordVideoSize = size
+	}
+}
+
+func WithStorageStateFile(path string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.StorageStateFile = path
+	}
+}
+
+// Save storage_state_file every interval seconds (0 for never) and whenever a page has loaded if onLoad is set
+func WithStorageStateAutoSave(interval float64, onLoad bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.StorageStateSaveInterval = interval
+		c.StorageStateSaveOnLoad = onLoad
+	}
+}
+
+// Proxy for this context only, e.g. a different egress per agent in a shared browser
+func WithContextProxy(proxy playwright.Proxy) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.Proxy = &proxy
+	}
+}
+
+// Emulate a playwright device, e.g. "iPhone 13" or "Pixel 7"
+func WithDevice(name string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.Device = name
+	}
+}
+
+func WithViewport(width int, height int) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.Viewport = &playwright.Size{Width: width, Height: height}
+	}
+}
+
+func WithUserAgent(userAgent string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.UserAgent = userAgent
+	}
+}
+
+func WithLocale(locale string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.Locale = locale
+	}
+}
+
+func WithTimezoneId(timezoneId string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.TimezoneId = timezoneId
+	}
+}
+
+func WithHttpCredentials(credentials playwright.HttpCredentials) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.HttpCredentials = &credentials
+	}
+}
+
+func WithIsMobile(isMobile bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.IsMobile = &isMobile
+	}
+}
+
+func WithHasTouch(hasTouch bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.HasTouch = &hasTouch
+	}
+}
+
+// Emulate a position, the geolocation permission is granted as well
+func WithGeolocation(latitude float64, longitude float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.Geolocation = &playwright.Geolocation{Latitude: latitude, Longitude: longitude}
+		if !slices.Contains(c.Permissions, "geolocation") {
+			c.Permissions = append(c.Permissions, "geolocation")
+		}
+	}
+}
+
+func WithPermissions(permissions ...string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.Permissions = permissions
+	}
+}
+
+func WithColorScheme(colorScheme string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.ColorScheme = colorScheme
+	}
+}
+
+func WithReducedMotion(reducedMotion string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.ReducedMotion = reducedMotion
+	}
+}
+
+func WithExtraHttpHeaders(headers map[string]string) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.ExtraHttpHeaders = headers
+	}
+}
+
+func WithOffline(offline bool) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.Offline = offline
+	}
+}
+
+func WithDeviceScaleFactor(deviceScaleFactor float64) ContextOption {
+	return func(c *BrowserContextConfig) {
+		c.DeviceScaleFactor = deviceScaleFactor
+	}
+}
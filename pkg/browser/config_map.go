@@ -0,0 +1,523 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// ConfigMap is the untyped form of the browser and context configuration, e.g. decoded from JSON.
+// Keys are documented on the fields of BrowserSettings and BrowserContextConfig.
+type ConfigMap = map[string]interface{}
+
+// Browser configuration in map form, as taken by NewBrowser. Keys are those of ConfigMap.
+type BrowserConfig = ConfigMap
+
+// Default browser configuration in map form
+func NewBrowserConfig() BrowserConfig {
+	return BrowserConfig{
+		"headless":         false,
+		"disable_security": false,
+		"browser_class":    BrowserClassChromium,
+	}
+}
+
+type configSetter[T any] func(config *T, key string, value interface{}) error
+
+var contextConfigSetters = map[string]configSetter[BrowserContextConfig]{
+	"cookies_file":                         setString(func(c *BrowserContextConfig) *string { return &c.CookiesFile }),
+	"storage_state_file":                   setString(func(c *BrowserContextConfig) *string { return &c.StorageStateFile }),
+	"storage_state_save_interval":          setFloat(func(c *BrowserContextConfig) *float64 { return &c.StorageStateSaveInterval }),
+	"storage_state_save_on_load":           setBool(func(c *BrowserContextConfig) *bool { return &c.StorageStateSaveOnLoad }),
+	"keep_alive":                           setBool(func(c *BrowserContextConfig) *bool { return &c.KeepAlive }),
+	"save_downloads_path":                  setString(func(c *BrowserContextConfig) *string { return &c.SaveDownloadsPath }),
+	"allowed_domains":                      setDomains(func(c *BrowserContextConfig) *[]string { return &c.AllowedDomains }),
+	"highlight_elements":                   setBool(func(c *BrowserContextConfig) *bool { return &c.HighlightElements }),
+	"viewport_expansion":                   setInt(func(c *BrowserContextConfig) *int { return &c.ViewportExpansion }),
+	"include_dynamic_attributes":           setBool(func(c *BrowserContextConfig) *bool { return &c.IncludeDynamicAttributes }),
+	"minimum_wait_page_load_time":          setFloat(func(c *BrowserContextConfig) *float64 { return &c.MinimumWaitPageLoadTime }),
+	"wait_for_network_idle_page_load_time": setFloat(func(c *BrowserContextConfig) *float64 { return &c.WaitForNetworkIdlePageLoadTime }),
+	"maximum_wait_page_load_time":          setFloat(func(c *BrowserContextConfig) *float64 { return &c.MaximumWaitPageLoadTime }),
+	"wait_between_actions":                 setFloat(func(c *BrowserContextConfig) *float64 { return &c.WaitBetweenActions }),
+	"click_timeout":                        setFloat(func(c *BrowserContextConfig) *float64 { return &c.ClickTimeout }),
+	"input_timeout":                        setFloat(func(c *BrowserContextConfig) *float64 { return &c.InputTimeout }),
+	"adaptive_waits":                       setBool(func(c *BrowserContextConfig) *bool { return &c.AdaptiveWaits }),
+	"dialog_policy":                        setString(func(c *BrowserContextConfig) *string { return &c.DialogPolicy }),
+	"dialog_prompt_text":                   setString(func(c *BrowserContextConfig) *string { return &c.DialogPromptText }),
+	"route_rules":                          setRouteRules,
+	"route_rules_file":                     setString(func(c *BrowserContextConfig) *string { return &c.RouteRulesFile }),
+	"save_har_path":                        setString(func(c *BrowserContextConfig) *string { return &c.SaveHarPath }),
+	"replay_har_path":                      setString(func(c *BrowserContextConfig) *string { return &c.ReplayHarPath }),
+	"replay_har_not_found":                 setString(func(c *BrowserContextConfig) *string { return &c.ReplayHarNotFound }),
+	"trace_path":                           setString(func(c *BrowserContextConfig) *string { return &c.TracePath }),
+	"trace_chunk_per_step":                 setBool(func(c *BrowserContextConfig) *bool { return &c.TraceChunkPerStep }),
+	"record_video_dir":                     setString(func(c *BrowserContextConfig) *string { return &c.RecordVideoDir }),
+	"record_video_size":                    setSize(func(c *BrowserContextConfig) **playwright.Size { return &c.RecordVideoSize }),
+	"context_proxy":                        setProxy(func(c *BrowserContextConfig) **playwright.Proxy { return &c.Proxy }),
+	"device":                               setString(func(c *BrowserContextConfig) *string { return &c.Device }),
+	"viewport":                             setSize(func(c *BrowserContextConfig) **playwright.Size { return &c.Viewport }),
+	"user_agent":                           setString(func(c *BrowserContextConfig) *string { return &c.UserAgent }),
+	"locale":                               setString(func(c *BrowserContextConfig) *string { return &c.Locale }),
+	"timezone_id":                          setString(func(c *BrowserContextConfig) *string { return &c.TimezoneId }),
+	"http_credentials":                     setHttpCredentials,
+	"is_mobile":                            setOptionalBool(func(c *BrowserContextConfig) **bool { return &c.IsMobile }),
+	"has_touch":                            setOptionalBool(func(c *BrowserContextConfig) **bool { return &c.HasTouch }),
+	"geolocation":                          setGeolocation,
+	"permissions":                          setStrings(func(c *BrowserContextConfig) *[]string { return &c.Permissions }),
+	"color_scheme":                         setString(func(c *BrowserContextConfig) *string { return &c.ColorScheme }),
+	"reduced_motion":                       setString(func(c *BrowserContextConfig) *string { return &c.ReducedMotion }),
+	"extra_http_headers":                   setStringMap(func(c *BrowserContextConfig) *map[string]string { return &c.ExtraHttpHeaders }),
+	"offline":                              setBool(func(c *BrowserContextConfig) *bool { return &c.Offline }),
+	"device_scale_factor":                  setFloat(func(c *BrowserContextConfig) *float64 { return &c.DeviceScaleFactor }),
+}
+
+var browserConfigSetters = map[string]configSetter[BrowserSettings]{
+	"headless":                setBool(func(c *BrowserSettings) *bool { return &c.Headless }),
+	"disable_security":        setBool(func(c *BrowserSettings) *bool { return &c.DisableSecurity }),
+	"deterministic_rendering": setBool(func(c *BrowserSettings) *bool { return &c.DeterministicRendering }),
+	"browser_class":           setString(func(c *BrowserSettings) *string { return &c.BrowserClass }),
+	"browser_binary_path":     setString(func(c *BrowserSettings) *string { return &c.BrowserBinaryPath }),
+	"cdp_url":                 setString(func(c *BrowserSettings) *string { return &c.CdpUrl }),
+	"wss_url":                 setString(func(c *BrowserSettings) *string { return &c.WssUrl }),
+	"user_data_dir":           setString(func(c *BrowserSettings) *string { return &c.UserDataDir }),
+	"profile_directory":       setString(func(c *BrowserSettings) *string { return &c.ProfileDirectory }),
+	"profiles_dir":            setString(func(c *BrowserSettings) *string { return &c.ProfilesDir }),
+	"profile":                 setString(func(c *BrowserSettings) *string { return &c.Profile }),
+	"remote_debugging_port":   setInt(func(c *BrowserSettings) *int { return &c.RemoteDebuggingPort }),
+	"attach_existing":         setBool(func(c *BrowserSettings) *bool { return &c.AttachExisting }),
+	"chrome_log_path":         setString(func(c *BrowserSettings) *string { return &c.ChromeLogPath }),
+	"extra_browser_args":      setStrings(func(c *BrowserSettings) *[]string { return &c.ExtraBrowserArgs }),
+	"proxy":                   setProxy(func(c *BrowserSettings) **playwright.Proxy { return &c.Proxy }),
+	"proxy_pool":              setProxyPool,
+}
+
+// Create a browser configuration from a map, starting from the defaults.
+// Unknown keys and values of the wrong type are reported as errors, and the result is validated.
+func BrowserConfigFromMap(m ConfigMap) (*BrowserSettings, error) {
+	config := NewBrowserSettings()
+	var errs []error
+	for _, key := range sortedKeys(m) {
+		if setter, ok := browserConfigSetters[key]; ok {
+			errs = append(errs, setter(config, key, m[key]))
+		} else if setter, ok := contextConfigSetters[key]; ok {
+			errs = append(errs, setter(&config.NewContextConfig, key, m[key]))
+		} else {
+			errs = append(errs, unknownKeyError(key, append(setterKeys(browserConfigSetters), setterKeys(contextConfigSetters)...)))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Create a context configuration from a map, starting from the defaults.
+// Unknown keys and values of the wrong type are reported as errors, and the result is validated.
+func ContextConfigFromMap(m ConfigMap) (*BrowserContextConfig, error) {
+	config := NewContextConfig()
+	var errs []error
+	for _, key := range sortedKeys(m) {
+		if setter, ok := contextConfigSetters[key]; ok {
+			errs = append(errs, setter(config, key, m[key]))
+		} else {
+			errs = append(errs, unknownKeyError(key, setterKeys(contextConfigSetters)))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func sortedKeys(m ConfigMap) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func setterKeys[T any](setters map[string]configSetter[T]) []string {
+	keys := make([]string, 0, len(setters))
+	for key := range setters {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Error for an unknown key, suggesting the closest known key for typos
+func unknownKeyError(key string, knownKeys []string) error {
+	suggestion := ""
+	bestDistance := 3 // only suggest keys with at most 2 edits
+	for _, knownKey := range knownKeys {
+		if distance := levenshtein(key, knownKey); distance < bestDistance || (distance == bestDistance && knownKey < suggestion) {
+			suggestion = knownKey
+			bestDistance = distance
+		}
+	}
+	if suggestion != "" {
+		return fmt.Errorf("unknown config key %q, did you mean %q?", key, suggestion)
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+func levenshtein(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(b)]
+}
+
+func typeError(key string, expected string, value interface{}) error {
+	return fmt.Errorf("config key %q expects %s, got %T", key, expected, value)
+}
+
+func setBool[T any](field func(*T) *bool) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		v, ok := value.(bool)
+		if !ok {
+			return typeError(key, "a bool", value)
+		}
+		*field(config) = v
+		return nil
+	}
+}
+
+// Setter of a bool that is unset by default, e.g. to tell an explicit false from the default
+func setOptionalBool[T any](field func(*T) **bool) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		v, ok := value.(bool)
+		if !ok {
+			return typeError(key, "a bool", value)
+		}
+		*field(config) = &v
+		return nil
+	}
+}
+
+func setString[T any](field func(*T) *string) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		switch v := value.(type) {
+		case string:
+			*field(config) = v
+		case *string:
+			if v != nil {
+				*field(config) = *v
+			}
+		case nil:
+			*field(config) = ""
+		default:
+			return typeError(key, "a string", value)
+		}
+		return nil
+	}
+}
+
+func setInt[T any](field func(*T) *int) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		switch v := value.(type) {
+		case int:
+			*field(config) = v
+		case float64:
+			// numbers decoded from JSON
+			if v != float64(int(v)) {
+				return typeError(key, "an integer", value)
+			}
+			*field(config) = int(v)
+		default:
+			return typeError(key, "an int", value)
+		}
+		return nil
+	}
+}
+
+func setFloat[T any](field func(*T) *float64) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		switch v := value.(type) {
+		case float64:
+			*field(config) = v
+		case int:
+			*field(config) = float64(v)
+		default:
+			return typeError(key, "a number", value)
+		}
+		return nil
+	}
+}
+
+func toStrings(key string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, typeError(key, "a list of strings", value)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	case nil:
+		return nil, nil
+	}
+	return nil, typeError(key, "a list of strings", value)
+}
+
+func setStrings[T any](field func(*T) *[]string) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		v, err := toStrings(key, value)
+		if err != nil {
+			return err
+		}
+		*field(config) = v
+		return nil
+	}
+}
+
+// Domains are given as a list or as a comma separated string
+func setDomains[T any](field func(*T) *[]string) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		if text, ok := value.(string); ok {
+			domains := []string{}
+			for _, domain := range strings.Split(text, ",") {
+				if domain = strings.TrimSpace(domain); domain != "" {
+					domains = append(domains, domain)
+				}
+			}
+			*field(config) = domains
+			return nil
+		}
+		return setStrings(field)(config, key, value)
+	}
+}
+
+func optionalMapString(key string, m map[string]interface{}, name string) (*string, error) {
+	value, ok := m[name]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, typeError(key+"."+name, "a string", value)
+	}
+	return &s, nil
+}
+
+func toProxy(key string, value interface{}) (*playwright.Proxy, error) {
+	switch v := value.(type) {
+	case playwright.Proxy:
+		return &v, nil
+	case *playwright.Proxy:
+		return v, nil
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		server, ok := v["server"].(string)
+		if !ok {
+			return nil, typeError(key+".server", "a string", v["server"])
+		}
+		proxy := &playwright.Proxy{Server: server}
+		var err error
+		if proxy.Bypass, err = optionalMapString(key, v, "bypass"); err != nil {
+			return nil, err
+		}
+		if proxy.Username, err = optionalMapString(key, v, "username"); err != nil {
+			return nil, err
+		}
+		if proxy.Password, err = optionalMapString(key, v, "password"); err != nil {
+			return nil, err
+		}
+		return proxy, nil
+	}
+	return nil, typeError(key, "a map with server, bypass, username and password", value)
+}
+
+func setProxy[T any](field func(*T) **playwright.Proxy) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		proxy, err := toProxy(key, value)
+		if err != nil {
+			return err
+		}
+		*field(config) = proxy
+		return nil
+	}
+}
+
+func setProxyPool(config *BrowserSettings, key string, value interface{}) error {
+	switch v := value.(type) {
+	case []playwright.Proxy:
+		config.ProxyPool = v
+	case nil:
+		config.ProxyPool = nil
+	case []interface{}:
+		pool := make([]playwright.Proxy, 0, len(v))
+		for i, item := range v {
+			proxy, err := toProxy(fmt.Sprintf("%s[%d]", key, i), item)
+			if err != nil {
+				return err
+			}
+			if proxy == nil {
+				return typeError(fmt.Sprintf("%s[%d]", key, i), "a map with server, bypass, username and password", item)
+			}
+			pool = append(pool, *proxy)
+		}
+		config.ProxyPool = pool
+	default:
+		return typeError(key, "a list of proxies", value)
+	}
+	return nil
+}
+
+func setHttpCredentials(config *BrowserContextConfig, key string, value interface{}) error {
+	switch v := value.(type) {
+	case playwright.HttpCredentials:
+		config.HttpCredentials = &v
+	case *playwright.HttpCredentials:
+		config.HttpCredentials = v
+	case nil:
+		config.HttpCredentials = nil
+	case map[string]interface{}:
+		username, ok := v["username"].(string)
+		if !ok {
+			return typeError(key+".username", "a string", v["username"])
+		}
+		password, ok := v["password"].(string)
+		if !ok {
+			return typeError(key+".password", "a string", v["password"])
+		}
+		config.HttpCredentials = &playwright.HttpCredentials{Username: username, Password: password}
+	default:
+		return typeError(key, "a map with username and password", value)
+	}
+	return nil
+}
+
+func setRouteRules(config *BrowserContextConfig, key string, value interface{}) error {
+	switch v := value.(type) {
+	case []RouteRule:
+		config.RouteRules = v
+	case nil:
+		config.RouteRules = nil
+	case []interface{}:
+		// decode the maps through JSON, so the keys are the json keys of RouteRule
+		data, err := json.Marshal(v)
+		if err != nil {
+			return typeError(key, "a list of route rules", value)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		var rules []RouteRule
+		if err := decoder.Decode(&rules); err != nil {
+			return fmt.Errorf("config key %q: %w", key, err)
+		}
+		config.RouteRules = rules
+	default:
+		return typeError(key, "a list of route rules", value)
+	}
+	return nil
+}
+
+func setSize[T any](field func(*T) **playwright.Size) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		switch v := value.(type) {
+		case playwright.Size:
+			*field(config) = &v
+		case *playwright.Size:
+			*field(config) = v
+		case nil:
+			*field(config) = nil
+		case map[string]interface{}:
+			size := &playwright.Size{}
+			if err := setInt(func(s *playwright.Size) *int { return &s.Width })(size, key+".width", v["width"]); err != nil {
+				return err
+			}
+			if err := setInt(func(s *playwright.Size) *int { return &s.Height })(size, key+".height", v["height"]); err != nil {
+				return err
+			}
+			*field(config) = size
+		default:
+			return typeError(key, "a map with width and height", value)
+		}
+		return nil
+	}
+}
+
+func setStringMap[T any](field func(*T) *map[string]string) configSetter[T] {
+	return func(config *T, key string, value interface{}) error {
+		switch v := value.(type) {
+		case map[string]string:
+			*field(config) = v
+		case nil:
+			*field(config) = nil
+		case map[string]interface{}:
+			result := make(map[string]string, len(v))
+			for name, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return typeError(key+"."+name, "a string", item)
+				}
+				result[name] = s
+			}
+			*field(config) = result
+		default:
+			return typeError(key, "a map of strings", value)
+		}
+		return nil
+	}
+}
+
+func setGeolocation(config *BrowserContextConfig, key string, value interface{}) error {
+	switch v := value.(type) {
+	case playwright.Geolocation:
+		config.Geolocation = &v
+	case *playwright.Geolocation:
+		config.Geolocation = v
+	case nil:
+		config.Geolocation = nil
+	case map[string]interface{}:
+		geolocation := &playwright.Geolocation{}
+		if err := setFloat(func(g *playwright.Geolocation) *float64 { return &g.Latitude })(geolocation, key+".latitude", v["latitude"]); err != nil {
+			return err
+		}
+		if err := setFloat(func(g *playwright.Geolocation) *float64 { return &g.Longitude })(geolocation, key+".longitude", v["longitude"]); err != nil {
+			return err
+		}
+		if accuracy, ok := v["accuracy"]; ok && accuracy != nil {
+			geolocation.Accuracy = new(float64)
+			if err := setFloat(func(g *playwright.Geolocation) *float64 { return g.Accuracy })(geolocation, key+".accuracy", accuracy); err != nil {
+				return err
+			}
+		}
+		config.Geolocation = geolocation
+	default:
+		return typeError(key, "a map with latitude, longitude and accuracy", value)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package browser
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestNewBrowserSettingsDefaults(t *testing.T) {
+	config := NewBrowserSettings()
+	if config.BrowserClass != BrowserClassChromium || config.Headless {
+		t.Errorf("unexpected defaults: %+v", config)
+	}
+	if !config.NewContextConfig.HighlightElements || !config.NewContextConfig.IncludeDynamicAttributes {
+		t.Errorf("unexpected context defaults: %+v", config.NewContextConfig)
+	}
+	if err := config.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBrowserSettingsOptions(t *testing.T) {
+	config := NewBrowserSettings(
+		WithHeadless(true),
+		WithExtraBrowserArgs("--a", "--b"),
+		WithNewContextConfig(WithCookiesFile("cookies.json"), WithAllowedDomains("example.com")),
+	)
+	if !config.Headless || len(config.ExtraBrowserArgs) != 2 {
+		t.Errorf("options not applied: %+v", config)
+	}
+	if config.NewContextConfig.CookiesFile != "cookies.json" || config.NewContextConfig.AllowedDomains[0] != "example.com" {
+		t.Errorf("context options not applied: %+v", config.NewContextConfig)
+	}
+
+	b := NewBrowserWithSettings(config)
+	bc := b.NewContext(WithAllowedDomains("example.org"))
+	if len(bc.Config.AllowedDomains) != 2 || len(config.NewContextConfig.AllowedDomains) != 1 {
+		t.Errorf("context options should not change the browser config: %v / %v", bc.Config.AllowedDomains, config.NewContextConfig.AllowedDomains)
+	}
+}
+
+func TestNewBrowserFromMap(t *testing.T) {
+	b := NewBrowser(BrowserConfig{"headless": true, "cookies_file": "cookies.json"})
+	if !b.Config.Headless || b.Config.BrowserClass != BrowserClassChromium || b.Config.NewContextConfig.CookiesFile != "cookies.json" {
+		t.Errorf("map config not applied over the defaults: %+v", b.Config)
+	}
+	if _, err := BrowserConfigFromMap(NewBrowserConfig()); err != nil {
+		t.Errorf("default map config should be valid: %v", err)
+	}
+
+	err := NewBrowser(BrowserConfig{"headles": true}).Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `did you mean "headless"?`) {
+		t.Errorf("expected Start to return the config error, got %v", err)
+	}
+}
+
+func TestContextStartValidatesOptions(t *testing.T) {
+	b := NewBrowserWithSettings(nil)
+	bc := b.NewContext(WithDialogPolicy("bogus"), WithClickTimeout(-1), WithContextProxy(playwright.Proxy{}))
+	_, err := bc.Start(context.Background())
+	if err == nil {
+		t.Fatal("expected invalid context options to fail Start")
+	}
+	for _, expected := range []string{"invalid context config", "dialog_policy", "click_timeout and input_timeout must be positive", "context_proxy requires a server"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err.Error())
+		}
+	}
+	if b.PlaywrightBrowser != nil || bc.Session != nil {
+		t.Error("the browser must not be started for an invalid context")
+	}
+}
+
+func TestBrowserSettingsValidate(t *testing.T) {
+	config := NewBrowserSettings(
+		WithBrowserClass("safari"),
+		WithCdpUrl("http://localhost:9222"),
+		WithWssUrl("ws://localhost:3000"),
+		WithNewContextConfig(WithViewportExpansion(-5), WithMinimumWaitPageLoadTime(10), WithDialogPolicy("ignore"), WithColorScheme("blue"), WithGeolocation(91, 0)),
+	)
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, expected := range []string{"browser_class", "cdp_url and wss_url", "viewport_expansion", "minimum_wait_page_load_time", "dialog_policy", "color_scheme", "geolocation.latitude"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err.Error())
+		}
+	}
+}
+
+func TestBrowserConfigFromMap(t *testing.T) {
+	config, err := BrowserConfigFromMap(ConfigMap{
+		"headless":                    true,
+		"extra_browser_args":          []interface{}{"--a"},
+		"proxy":                       map[string]interface{}{"server": "http://proxy:8080", "username": "user"},
+		"proxy_pool":                  []interface{}{map[string]interface{}{"server": "http://pool-a:8080"}, map[string]interface{}{"server": "http://pool-b:8080"}},
+		"context_proxy":               map[string]interface{}{"server": "socks5://own:1080"},
+		"cookies_file":                "cookies.json",
+		"allowed_domains":             "example.com, example.org",
+		"viewport_expansion":          float64(500),
+		"maximum_wait_page_load_time": 10,
+		"route_rules":                 []interface{}{map[string]interface{}{"resource_types": []interface{}{"font"}, "action": "block"}},
+		"record_video_size":           map[string]interface{}{"width": float64(640), "height": 480},
+		"geolocation":                 map[string]interface{}{"latitude": 52.52, "longitude": 13.405, "accuracy": 10},
+		"permissions":                 []interface{}{"geolocation", "notifications"},
+		"extra_http_headers":          map[string]interface{}{"X-Region": "eu"},
+		"device_scale_factor":         2,
+		"device":                      "Pixel 7",
+		"viewport":                    map[string]interface{}{"width": 412, "height": 839},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.Headless || config.ExtraBrowserArgs[0] != "--a" {
+		t.Errorf("browser keys not applied: %+v", config)
+	}
+	if config.Proxy == nil || config.Proxy.Server != "http://proxy:8080" || *config.Proxy.Username != "user" || config.Proxy.Password != nil {
+		t.Errorf("proxy not applied: %+v", config.Proxy)
+	}
+	if len(config.ProxyPool) != 2 || config.ProxyPool[1].Server != "http://pool-b:8080" {
+		t.Errorf("proxy_pool not applied: %+v", config.ProxyPool)
+	}
+	contextConfig := config.NewContextConfig
+	if contextConfig.CookiesFile != "cookies.json" || len(contextConfig.AllowedDomains) != 2 || contextConfig.ViewportExpansion != 500 || contextConfig.MaximumWaitPageLoadTime != 10 ||
+		len(contextConfig.RouteRules) != 1 || contextConfig.RouteRules[0].ResourceTypes[0] != "font" ||
+		contextConfig.RecordVideoSize == nil || *contextConfig.RecordVideoSize != (playwright.Size{Width: 640, Height: 480}) ||
+		contextConfig.Geolocation == nil || contextConfig.Geolocation.Latitude != 52.52 || *contextConfig.Geolocation.Accuracy != 10 ||
+		len(contextConfig.Permissions) != 2 || contextConfig.ExtraHttpHeaders["X-Region"] != "eu" || contextConfig.DeviceScaleFactor != 2 ||
+		contextConfig.Device != "Pixel 7" || contextConfig.Proxy == nil || contextConfig.Proxy.Server != "socks5://own:1080" || *contextConfig.Viewport != (playwright.Size{Width: 412, Height: 839}) {
+		t.Errorf("context keys not applied: %+v", contextConfig)
+	}
+}
+
+func TestBrowserConfigFromMapErrors(t *testing.T) {
+	_, err := BrowserConfigFromMap(ConfigMap{
+		"cookie_file": "cookies.json",
+		"headless":    "yes",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `unknown config key "cookie_file", did you mean "cookies_file"?`) {
+		t.Errorf("expected suggestion for typo, got %q", err.Error())
+	}
+	if !strings.Contains(err.Error(), `config key "headless" expects a bool, got string`) {
+		t.Errorf("expected type error, got %q", err.Error())
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"headless": true})
+	if err == nil {
+		t.Error("browser keys should not be accepted for a context config")
+	}
+
+	_, err = BrowserConfigFromMap(ConfigMap{"browser_class": "firefox", "browser_binary_path": "/usr/bin/chrome"})
+	if err == nil {
+		t.Error("expected validation error for browser_binary_path with firefox")
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"route_rules": []interface{}{
+		map[string]interface{}{"url": "**/*.png", "action": "block"},
+		map[string]interface{}{"url": "**/api/**", "action": "mock"},
+		map[string]interface{}{"action": "headers", "header": map[string]interface{}{"x-test": "1"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), `unknown field "header"`) {
+		t.Errorf("expected error for unknown route rule field, got %v", err)
+	}
+	_, err = ContextConfigFromMap(ConfigMap{"route_rules": []interface{}{
+		map[string]interface{}{"url": "**/api/**", "action": "mock", "resource_types": []interface{}{"images"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "route_rules[0]: mock requires a file") || !strings.Contains(err.Error(), `unknown resource type "images"`) {
+		t.Errorf("expected validation error for route rule, got %v", err)
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"route_rules": []interface{}{
+		map[string]interface{}{"url": "**/api/**", "action": "mock", "file": filepath.Join(t.TempDir(), "missing.json")},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "route_rules[0]: mock file") {
+		t.Errorf("expected validation error for a missing mock file, got %v", err)
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"save_har_path": "har", "replay_har_path": "run.har", "replay_har_not_found": "ignore"})
+	if err == nil || !strings.Contains(err.Error(), "can not be combined") || !strings.Contains(err.Error(), "replay_har_not_found") {
+		t.Errorf("expected validation errors for the HAR options, got %v", err)
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"trace_chunk_per_step": true})
+	if err == nil || !strings.Contains(err.Error(), "trace_chunk_per_step requires trace_path") {
+		t.Errorf("expected validation error for trace_chunk_per_step without trace_path, got %v", err)
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"record_video_size": map[string]interface{}{"width": "640"}})
+	if err == nil || !strings.Contains(err.Error(), `config key "record_video_size.width" expects an int`) {
+		t.Errorf("expected type error for record_video_size, got %v", err)
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"extra_http_headers": map[string]interface{}{"X-Retries": 3}})
+	if err == nil || !strings.Contains(err.Error(), `config key "extra_http_headers.X-Retries" expects a string`) {
+		t.Errorf("expected type error for extra_http_headers, got %v", err)
+	}
+
+	_, err = BrowserConfigFromMap(ConfigMap{"proxy_pool": []interface{}{map[string]interface{}{"server": "http://pool-a:8080"}, "http://pool-b:8080"}})
+	if err == nil || !strings.Contains(err.Error(), `config key "proxy_pool[1]" expects a map`) {
+		t.Errorf("expected type error for proxy_pool, got %v", err)
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"viewport": map[string]interface{}{"width": 0, "height": 800}})
+	if err == nil || !strings.Contains(err.Error(), "viewport requires a positive width and height") {
+		t.Errorf("expected validation error for viewport, got %v", err)
+	}
+
+	_, err = ContextConfigFromMap(ConfigMap{"storage_state_save_interval": 30})
+	if err == nil || !strings.Contains(err.Error(), "require storage_state_file") {
+		t.Errorf("expected validation error for storage_state_save_interval without storage_state_file, got %v", err)
+	}
+
+	_, err = BrowserConfigFromMap(ConfigMap{"profile": "customer-a", "user_data_dir": "/tmp/chrome"})
+	if err == nil || !strings.Contains(err.Error(), "profile requires profiles_dir") || !strings.Contains(err.Error(), "can not be used together") {
+		t.Errorf("expected validation errors for profile, got %v", err)
+	}
+
+	_, err = BrowserConfigFromMap(ConfigMap{"browser_binary_path": "/usr/bin/chrome", "attach_existing": true})
+	if err == nil || !strings.Contains(err.Error(), "attach_existing requires") {
+		t.Errorf("expected validation error for attach_existing without remote_debugging_port, got %v", err)
+	}
+}
+
+func TestBuiltinLaunchArgsWebkitPersistent(t *testing.T) {
+	b := NewBrowserWithSettings(NewBrowserSettings(WithBrowserClass(BrowserClassWebkit)))
+	if args := b.builtinLaunchArgs(false); !slices.Contains(args, "--no-startup-window") {
+		t.Errorf("expected the webkit args, got %v", args)
+	}
+	if args := b.builtinLaunchArgs(true); slices.Contains(args, "--no-startup-window") {
+		t.Errorf("a persistent webkit context needs its startup window, got %v", args)
+	}
+}
+
+func TestContextEmulationExplicitOverridesDevice(t *testing.T) {
+	devices := map[string]*playwright.DeviceDescriptor{
+		"Phone": {UserAgent: "phone", Viewport: &playwright.Size{Width: 390, Height: 844}, DeviceScaleFactor: 3, IsMobile: true, HasTouch: true},
+	}
+	emulation, err := newContextEmulation(devices, NewContextConfig(WithDevice("Phone")), BrowserClassChromium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !emulation.mobile || !*emulation.isMobile || !*emulation.hasTouch {
+		t.Error("expected the device preset to be mobile with touch")
+	}
+
+	emulation, err = newContextEmulation(devices, NewContextConfig(WithDevice("Phone"), WithIsMobile(false), WithHasTouch(false)), BrowserClassChromium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if emulation.mobile || *emulation.isMobile || *emulation.hasTouch {
+		t.Error("expected explicit false options to override the device preset")
+	}
+
+	config, err := ContextConfigFromMap(ConfigMap{"device": "Phone", "is_mobile": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if emulation, _ = newContextEmulation(devices, config, BrowserClassChromium); emulation.mobile || !*emulation.hasTouch {
+		t.Error("expected is_mobile false from the map to override only is_mobile of the device preset")
+	}
+}
@@ -1,16 +1,17 @@
 package browser
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	neturl "net/url"
 	"os"
-	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nerdface-ai/browser-use-go/internals/dom"
 	"github.com/nerdface-ai/browser-use-go/internals/utils"
@@ -97,12 +98,28 @@ type BrowserContextState struct {
 
 type BrowserContext struct {
 	ContextId        string
-	Config           BrowserConfig
+	Config           *BrowserContextConfig
 	Browser          *Browser
 	Session          *BrowserSession
 	State            *BrowserContextState
 	ActiveTab        playwright.Page
 	pageEventHandler func(page playwright.Page)
+	pageLoadWaitTime time.Duration
+	network          *networkTracker
+	downloads        *downloadManager
+	browserLogs      *browserLogCollector
+	router           *router
+	harPaths         []string
+	tracer           *tracer
+	tracePaths       []string
+	videos           *videoRecorder
+	videoName        string
+	videoPaths       []string
+	storage          *storageStateSaver
+	dialogs          *dialogRecorder
+	emulation        *contextEmulation // nil for existing contexts of connected browsers
+	proxy            *playwright.Proxy // context_proxy or the proxy picked from proxy_pool
+	pageLoadAverage  time.Duration
 }
 
 func (bc *BrowserContext) ConvertSimpleXpathToCssSelector(xpath string) string {
@@ -117,13 +134,23 @@ func (bc *BrowserContext) GetState(cacheClickableElementsHashes bool) *BrowserSt
 	/* Get the current state of the browser
 	cache_clickable_elements_hashes: bool
 		If True, cache the clickable elements hashes for the current state. This is used to calculate which elements are new to the llm (from last message) -> reduces token usage.
+	The browser logs and dialogs of the state are the ones since the last GetState, they are not returned again.
 	*/
+	return bc.getState(cacheClickableElementsHashes, true)
+}
+
+// Get the current state like GetState(false), but keep the browser logs and dialogs for the next GetState.
+// Used for states taken between the actions of a step, which are not shown to the model.
+func (bc *BrowserContext) PeekState() *BrowserState {
+	return bc.getState(false, false)
+}
 
+func (bc *BrowserContext) getState(cacheClickableElementsHashes bool, takeEvents bool) *BrowserState {
 	bc.waitForPageAndFramesLoad(nil)
 	page := bc.GetCurrentPage()
 
 	session := bc.GetSession()
-	updatedState := bc.getUpdatedState(page)
+	updatedState := bc.getUpdatedState(page, takeEvents)
 
 	if cacheClickableElementsHashes {
 		clickableElementProcessor := &dom.ClickableElementProcessor{}
@@ -149,13 +176,13 @@ func (bc *BrowserContext) GetState(cacheClickableElementsHashes bool) *BrowserSt
 	return updatedState
 }
 
-func (bc *BrowserContext) getUpdatedState(page playwright.Page) *BrowserState {
+func (bc *BrowserContext) getUpdatedState(page playwright.Page, takeEvents bool) *BrowserState {
 	domService := dom.NewDomService(page)
 	focus_element := -1 // default
 	content, err := domService.GetClickableElements(
-		utils.GetDefaultValue(bc.Config, "highlight_elements", true),
+		bc.Config.HighlightElements,
 		focus_element,
-		utils.GetDefaultValue(bc.Config, "viewport_expansion", 0),
+		bc.Config.ViewportExpansion,
 	)
 	if err != nil {
 		log.Warnf("Failed to get clickable elements: %s", err)
@@ -172,7 +199,22 @@ func (bc *BrowserContext) getUpdatedState(page playwright.Page) *BrowserState {
 		log.Warnf("Failed to get scroll info: %s", err)
 	}
 
+	viewport, err := bc.getViewportInfo(page)
+	if err != nil {
+		log.Warnf("Failed to get viewport: %s", err)
+	}
+
 	title, _ := page.Title()
+	browserLogs := []*BrowserLogEntry{}
+	if bc.browserLogs != nil && takeEvents {
+		browserLogs = bc.browserLogs.take()
+	} else if bc.browserLogs != nil {
+		browserLogs = bc.browserLogs.peek()
+	}
+	dialogs := bc.dialogs.peek()
+	if takeEvents {
+		dialogs = bc.dialogs.take()
+	}
 	// updated_state
 	currentState := BrowserState{
 		ElementTree:   content.ElementTree,
@@ -183,7 +225,11 @@ func (bc *BrowserContext) getUpdatedState(page playwright.Page) *BrowserState {
 		Screenshot:    screenshot,
 		PixelAbove:    pixelsAbove,
 		PixelBelow:    pixelsBelow,
-		BrowserErrors: []string{},
+		Viewport:      viewport,
+		BrowserErrors: BrowserLogsDigest(browserLogs),
+		BrowserLogs:   browserLogs,
+		Downloads:     bc.Downloads(),
+		Dialogs:       dialogs,
 	}
 	return &currentState
 }
@@ -241,15 +287,28 @@ func (bc *BrowserContext) GetScrollInfo(page playwright.Page) (int, int, error)
 	return pixelsAbove, pixelsBelow, nil
 }
 
+// Get the session, initializing it if needed. Panics if the session can not be initialized, use Start to handle errors.
 func (bc *BrowserContext) GetSession() *BrowserSession {
-	if bc.Session == nil {
-		session, err := bc.initializeSession()
-		if err != nil {
-			panic(err)
-		}
-		return session
+	session, err := bc.Start(context.Background())
+	if err != nil {
+		panic(err)
 	}
-	return bc.Session
+	return session
+}
+
+// Start the browser if needed and initialize the session. Starting a started context returns the current session.
+// The configuration of the context, including the options given to Browser.NewContext, is validated first.
+func (bc *BrowserContext) Start(ctx context.Context) (*BrowserSession, error) {
+	if bc.Session != nil {
+		return bc.Session, nil
+	}
+	if err := bc.Config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid context config: %w", err)
+	}
+	if err := bc.Browser.Start(ctx); err != nil {
+		return nil, err
+	}
+	return bc.initializeSession()
 }
 
 // Get the current page
@@ -267,19 +326,34 @@ func (bc *BrowserContext) Close() {
 		bc.pageEventHandler = nil
 	}
 
-	if bc.Config["cookies_file"] != nil {
+	if bc.Config.CookiesFile != "" {
 		go bc.SaveCookies()
 	}
+	bc.SaveStorageState()
+	bc.storage.detach()
+	bc.stopTracing()
 
-	if keepAlive, ok := bc.Config["keep_alive"].(bool); (ok && !keepAlive) || !ok {
+	// the persistent context belongs to the browser and is closed with it
+	if !bc.Config.KeepAlive && bc.Session.Context != bc.Browser.persistentContext {
 		err := bc.Session.Context.Close()
 		if err != nil {
 			log.Debugf("🪨  Failed to close browser context: %s", err)
 		}
+		// videos are complete once the context is closed
+		bc.saveVideos()
+		if bc.downloads != nil {
+			bc.downloads.cleanup()
+		}
+	} else if bc.videos != nil {
+		log.Warn("⚠️ Videos of a context kept alive are not saved")
+		bc.videos = nil
 	}
 
 	// Dereference everything
 	bc.Session = nil
+	bc.network = nil
+	bc.browserLogs = nil
+	bc.router.detach()
 	bc.ActiveTab = nil
 	bc.pageEventHandler = nil
 }
@@ -355,7 +429,7 @@ func (bc *BrowserContext) GetLocateElement(element *dom.DOMElementNode) playwrig
 			iframes = append(iframes, item)
 		}
 	}
-	includeDynamicAttributes := utils.GetDefaultValue(bc.Config, "include_dynamic_attributes", true)
+	includeDynamicAttributes := bc.Config.IncludeDynamicAttributes
 	for _, parent := range iframes {
 		cssSelector := bc.EnhancedCssSelectorForElement(parent, includeDynamicAttributes)
 		if currentFrame != nil {
@@ -394,9 +468,8 @@ func (bc *BrowserContext) ClickElementNode(elementNode *dom.DOMElementNode) (*st
 
 	// Performs the actual click, handling both download and navigation scenarios.
 	performClick := func(clickFunc func() error) (*string, error) {
-		saveDownloadPath, ok := bc.Config["save_downloads_path"].(string)
-		if ok {
-			downloadInfo, err := page.ExpectDownload(clickFunc, playwright.PageExpectDownloadOptions{Timeout: playwright.Float(3000)})
+		if bc.Config.SaveDownloadsPath != "" {
+			download, err := page.ExpectDownload(clickFunc, playwright.PageExpectDownloadOptions{Timeout: playwright.Float(3000)})
 			if err != nil {
 				if strings.HasPrefix(err.Error(), "timeout:") {
 					log.Debug("No download triggered within timeout. Checking navigation...")
@@ -406,20 +479,16 @@ func (bc *BrowserContext) ClickElementNode(elementNode *dom.DOMElementNode) (*st
 				}
 				return nil, err
 			} else {
-				suggestedFilename := downloadInfo.SuggestedFilename()
-				uniqueFilename := bc.getUniqueFilename(saveDownloadPath, suggestedFilename)
-				downloadPath := filepath.Join(saveDownloadPath, uniqueFilename)
-				err := downloadInfo.SaveAs(downloadPath)
-				if err != nil {
-					return nil, err
+				downloadInfo := bc.downloads.wait(download, DOWNLOAD_WAIT_TIMEOUT)
+				if downloadInfo.Status == DownloadFailed {
+					return nil, &BrowserError{Message: "Download failed: " + downloadInfo.Error}
 				}
-				log.Debugf("⬇️  Download triggered. Saved file to: %s", downloadPath)
-				return &downloadPath, nil
+				return &downloadInfo.Path, nil
 			}
 		} else {
 			newPage, err := bc.GetSession().Context.ExpectPage(func() error {
 				return clickFunc()
-			}, playwright.BrowserContextExpectPageOptions{Timeout: playwright.Float(1500)})
+			}, playwright.BrowserContextExpectPageOptions{Timeout: bc.timeoutMs(bc.Config.ClickTimeout)})
 			if err != nil {
 				if strings.HasPrefix(err.Error(), "timeout:") {
 					page.WaitForLoadState()
@@ -437,7 +506,7 @@ func (bc *BrowserContext) ClickElementNode(elementNode *dom.DOMElementNode) (*st
 
 	return performClick(func() error {
 		// Use First() to handle cases where the locator matches multiple elements
-		return elementLocator.First().Click(playwright.LocatorClickOptions{Timeout: playwright.Float(1500)})
+		return elementLocator.First().Click(playwright.LocatorClickOptions{Timeout: bc.timeoutMs(bc.Config.ClickTimeout)})
 	})
 }
 
@@ -454,13 +523,13 @@ func (bc *BrowserContext) InputTextElementNode(elementNode *dom.DOMElementNode,
 
 	// Ensure element is ready for input
 	selectorState := playwright.WaitForSelectorState("visible")
-	locator.WaitFor(playwright.LocatorWaitForOptions{State: &selectorState, Timeout: playwright.Float(1000)})
+	locator.WaitFor(playwright.LocatorWaitForOptions{State: &selectorState, Timeout: bc.timeoutMs(bc.Config.InputTimeout)})
 	isHidden, err := locator.IsHidden()
 	if err != nil {
 		return &BrowserError{Message: "Failed to check if element is hidden: " + elementNode.Xpath}
 	}
 	if !isHidden {
-		locator.ScrollIntoViewIfNeeded(playwright.LocatorScrollIntoViewIfNeededOptions{Timeout: playwright.Float(1000)})
+		locator.ScrollIntoViewIfNeeded(playwright.LocatorScrollIntoViewIfNeededOptions{Timeout: bc.timeoutMs(bc.Config.InputTimeout)})
 	}
 
 	// Get element properties to determine input method
@@ -492,25 +561,89 @@ func (bc *BrowserContext) InputTextElementNode(elementNode *dom.DOMElementNode,
 
 func (bc *BrowserContext) initializeSession() (*BrowserSession, error) {
 	log.Debugf("🌎  Initializing new browser context with id: %s", bc.ContextId)
-	pwBrowser := bc.Browser.GetPlaywrightBrowser()
-
-	context, err := bc.createContext(pwBrowser)
+	context, err := bc.createContext(bc.Browser.PlaywrightBrowser)
 	if err != nil {
 		return nil, err
 	}
 	bc.pageEventHandler = nil
 
-	pages := context.Pages()
+	// the session is only published once the context is fully set up, a failed setup leaves no half-initialized session behind
+	if err := bc.attachContext(context); err != nil {
+		bc.discardContext(context)
+		return nil, err
+	}
+	activePage, err := bc.openActivePage(context)
+	if err != nil {
+		bc.discardContext(context)
+		return nil, err
+	}
+
 	bc.Session = &BrowserSession{
 		Context:     context,
 		CachedState: nil,
 	}
+	bc.ActiveTab = activePage
+
+	return bc.Session, nil
+}
 
+// Attach the trackers, routes and tracing to a new context
+func (bc *BrowserContext) attachContext(context playwright.BrowserContext) error {
+	bc.network = newNetworkTracker()
+	bc.network.attach(context)
+	bc.browserLogs = newBrowserLogCollector()
+	bc.browserLogs.attach(context)
+	downloads, err := newDownloadManager(bc.Config.SaveDownloadsPath)
+	if err != nil {
+		return err
+	}
+	bc.downloads = downloads
+	bc.downloads.attach(context)
+	bc.dialogs.attach(context)
+	if err := bc.ReloadRouteRules(); err != nil {
+		return err
+	}
+	if err := bc.router.attach(context); err != nil {
+		return err
+	}
+	if err := bc.startTracing(context); err != nil {
+		return err
+	}
+	if bc.Config.StorageStateFile != "" {
+		bc.storage.attach(bc, context)
+	}
+	return nil
+}
+
+// Undo a failed session setup: reset the trackers and close the context like Close would
+func (bc *BrowserContext) discardContext(context playwright.BrowserContext) {
+	bc.storage.detach()
+	// closing the context ends the tracing, the trace of a failed setup is not saved
+	bc.tracer = nil
+	bc.router.detach()
+	bc.network = nil
+	bc.browserLogs = nil
+	if bc.downloads != nil {
+		bc.downloads.cleanup()
+		bc.downloads = nil
+	}
+	bc.videos = nil
+	bc.proxy = nil
+	if !bc.Config.KeepAlive && context != bc.Browser.persistentContext {
+		if err := context.Close(); err != nil {
+			log.Debugf("🪨  Failed to close browser context: %s", err)
+		}
+	}
+}
+
+// Page the session starts on: the saved CDP target, an existing page or a new blank page
+func (bc *BrowserContext) openActivePage(context playwright.BrowserContext) (playwright.Page, error) {
+	pages := context.Pages()
 	var activePage playwright.Page = nil
-	if bc.Browser.Config["cdp_url"] != nil {
+	if bc.Browser.Config.CdpUrl != "" {
 		// If we have a saved target ID, try to find and activate it
 		if bc.State.TargetId != nil {
-			targets := bc.getCdpTargets()
+			targets := bc.cdpTargets(context)
 			for _, target := range targets {
 				if target["targetId"] == *bc.State.TargetId {
 					// Find matching page by URL
@@ -531,6 +664,7 @@ func (bc *BrowserContext) initializeSession() (*BrowserSession, error) {
 			activePage = pages[0]
 			log.Debugf("🔍  Using existing page: %s", activePage.URL())
 		} else {
+			var err error
 			activePage, err = context.NewPage()
 			if err != nil {
 				return nil, err
@@ -540,8 +674,8 @@ func (bc *BrowserContext) initializeSession() (*BrowserSession, error) {
 		}
 
 		// Get target ID for the active page
-		if bc.Browser.Config["cdp_url"] != nil {
-			targets := bc.getCdpTargets()
+		if bc.Browser.Config.CdpUrl != "" {
+			targets := bc.cdpTargets(context)
 			for _, target := range targets {
 				if target["url"] == activePage.URL() {
 					bc.State.TargetId = playwright.String(activePage.URL())
@@ -553,14 +687,11 @@ func (bc *BrowserContext) initializeSession() (*BrowserSession, error) {
 	log.Debugf("🫨  Bringing tab to front: %s", activePage.URL())
 	activePage.BringToFront()
 	activePage.WaitForLoadState() // 'load'
-
-	bc.ActiveTab = activePage
-
-	return bc.Session, nil
+	return activePage, nil
 }
 
 func (bc *BrowserContext) onPage(page playwright.Page) {
-	if bc.Browser.Config["cdp_url"] != nil {
+	if bc.Browser.Config.CdpUrl != "" {
 		page.Reload()
 	}
 	page.WaitForLoadState()
@@ -577,10 +708,17 @@ func (bc *BrowserContext) onPage(page playwright.Page) {
 
 // Get all CDP targets directly using CDP protocol
 func (bc *BrowserContext) getCdpTargets() []map[string]interface{} {
-	if bc.Browser.Config["cdp_url"] == nil || bc.Session == nil {
+	if bc.Session == nil {
+		return []map[string]interface{}{}
+	}
+	return bc.cdpTargets(bc.Session.Context)
+}
+
+func (bc *BrowserContext) cdpTargets(context playwright.BrowserContext) []map[string]interface{} {
+	if bc.Browser.Config.CdpUrl == "" || !bc.Browser.supportsCdp() {
 		return []map[string]interface{}{}
 	}
-	pages := bc.Session.Context.Pages()
+	pages := context.Pages()
 	if len(pages) == 0 {
 		return []map[string]interface{}{}
 	}
@@ -610,34 +748,15 @@ func (bc *BrowserContext) addNewPageListener(context playwright.BrowserContext)
 	context.OnPage(bc.pageEventHandler)
 }
 
-// Generate a unique filename by appending (1), (2), etc., if a file already exists.
-func (bc *BrowserContext) getUniqueFilename(directory, filename string) string {
-	ext := filepath.Ext(filename)
-	base := strings.TrimSuffix(filename, ext)
-	newFilename := filename
-	counter := 1
-
-	for {
-		fullPath := filepath.Join(directory, newFilename)
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-			break
-		}
-		newFilename = fmt.Sprintf("%s (%d)%s", base, counter, ext)
-		counter++
-	}
-	return newFilename
-}
-
 // Check if a URL is allowed based on the whitelist configuration
 func (bc *BrowserContext) isUrlAllowed(url string) bool {
-	allowedDomainsText, ok := bc.Config["allowed_domains"].(string)
-	if !ok || allowedDomainsText == "" {
+	if len(bc.Config.AllowedDomains) == 0 {
 		return true
 	}
 
-	allowedDomains := strings.Split(allowedDomainsText, ",")
-	for i := range allowedDomains {
-		allowedDomains[i] = strings.ToLower(strings.TrimSpace(allowedDomains[i]))
+	allowedDomains := make([]string, len(bc.Config.AllowedDomains))
+	for i, allowedDomain := range bc.Config.AllowedDomains {
+		allowedDomains[i] = strings.ToLower(strings.TrimSpace(allowedDomain))
 	}
 
 	// Special case: Allow 'about:blank' explicitly
@@ -679,22 +798,50 @@ func (bc *BrowserContext) checkAndHandleNavigation(page playwright.Page) error {
 	return nil
 }
 
-// TODO(MID): implement waitForPageAndFramesLoad
+// Wait for the network of the current page to become idle, then make sure at least
+// minimum_wait_page_load_time (or timeoutOverwrite seconds) have passed since the start.
 func (bc *BrowserContext) waitForPageAndFramesLoad(timeoutOverwrite *float64) error {
-	// maxTime := 0.25
-	// if timeoutOverwrite != nil {
-	// 	maxTime = *timeoutOverwrite
-	// }
-	// log.Debug("🪨  Waiting for page and frames to load for %f seconds", maxTime)
-	bc.waitForStableNetwork()
+	startTime := time.Now()
+	defer func() {
+		bc.pageLoadWaitTime += time.Since(startTime)
+	}()
+
 	page := bc.GetCurrentPage()
-	bc.checkAndHandleNavigation(page)
+	bc.waitForStableNetwork(page)
+	bc.recordPageLoad(time.Since(startTime))
+	if err := bc.checkAndHandleNavigation(page); err != nil {
+		return err
+	}
+
+	minTime := bc.Config.MinimumWaitPageLoadTime
+	if timeoutOverwrite != nil {
+		minTime = *timeoutOverwrite
+	}
+	elapsed := time.Since(startTime)
+	log.Debugf("⏳  Page loaded in %s", elapsed)
+	if remaining := seconds(minTime) - elapsed; remaining > 0 {
+		time.Sleep(remaining)
+	}
 	return nil
 }
 
+// Files downloaded by the pages of this context. Without save_downloads_path they are saved to a temporary
+// directory that is removed when the context is closed.
+func (bc *BrowserContext) Downloads() []*DownloadInfo {
+	if bc.downloads == nil {
+		return []*DownloadInfo{}
+	}
+	return bc.downloads.list()
+}
+
+// Total time spent waiting for pages to load in this context
+func (bc *BrowserContext) PageLoadWaitTime() time.Duration {
+	return bc.pageLoadWaitTime
+}
+
 func (bc *BrowserContext) LoadCookies(context playwright.BrowserContext) error {
-	cookiesFile, ok := bc.Config["cookies_file"].(string)
-	if !ok || !utils.FileExists(cookiesFile) {
+	cookiesFile := bc.Config.CookiesFile
+	if cookiesFile == "" || !utils.FileExists(cookiesFile) {
 		return nil
 	}
 	f, err := os.Open(cookiesFile)
@@ -718,77 +865,147 @@ func (bc *BrowserContext) LoadCookies(context playwright.BrowserContext) error {
 
 // current cookies to file
 func (bc *BrowserContext) SaveCookies() error {
-	cookiesFile, ok := bc.Config["cookies_file"].(string)
-	if bc.Session != nil && bc.Session.Context != nil && ok {
+	cookiesFile := bc.Config.CookiesFile
+	if bc.Session != nil && bc.Session.Context != nil && cookiesFile != "" {
 		cookies, err := bc.Session.Context.Cookies()
 		if err != nil {
 			log.Warnf("❌  Failed to save cookies: %s", err.Error())
 			return err
 		}
 		log.Debugf("🍪  Saving %d cookies to %s", len(cookies), cookiesFile)
-		// Check if the path is a directory and create it if necessary
-		dirname := filepath.Dir(cookiesFile)
-		if dirname != "" {
-			os.MkdirAll(dirname, 0755)
+		data, err := json.Marshal(cookies)
+		if err == nil {
+			err = utils.WriteFileAtomic(cookiesFile, data, 0644)
 		}
-
-		f, err := os.Create(cookiesFile)
 		if err != nil {
 			log.Warnf("❌  Failed to save cookies: %s", err.Error())
 			return err
 		}
-		defer f.Close()
-
-		if err := json.NewEncoder(f).Encode(cookies); err != nil {
-			log.Warnf("❌  Failed to save cookies: %s", err.Error())
-			return err
-		}
 	}
 
 	return nil
 }
 
-// TODO(MID): implement waitForStableNetwork
-func (bc *BrowserContext) waitForStableNetwork() error {
-	return nil
-}
-
 // Creates a new browser context with anti-detection measures and loads cookies if available.
 func (bc *BrowserContext) createContext(browser playwright.Browser) (playwright.BrowserContext, error) {
 	var context playwright.BrowserContext
-	var err error
-	if bc.Browser.Config["cdp_url"] != nil && len(browser.Contexts()) > 0 {
+	storageState, err := bc.loadStorageState()
+	if err != nil {
+		return nil, err
+	}
+	var proxy *playwright.Proxy
+	if bc.Browser.persistentContext == nil {
+		if proxy, err = bc.selectProxy(); err != nil {
+			return nil, err
+		}
+	}
+	reused := true
+	var harPath *string
+	if bc.Browser.persistentContext != nil {
+		context = bc.Browser.persistentContext
+		bc.emulation = bc.Browser.persistentEmulation
+	} else if bc.Browser.Config.CdpUrl != "" && len(browser.Contexts()) > 0 && proxy == nil {
 		context = browser.Contexts()[0]
-	} else if bc.Browser.Config["browser_binary_path"] != nil && len(browser.Contexts()) > 0 {
+	} else if bc.Browser.Config.BrowserBinaryPath != "" && len(browser.Contexts()) > 0 && proxy == nil {
 		context = browser.Contexts()[0]
 	} else {
+		harPath, err = bc.newHarPath()
+		if err != nil {
+			return nil, err
+		}
+		reused = false
+		var initialStorage *playwright.OptionalStorageState
+		if storageState != nil {
+			initialStorage = &playwright.OptionalStorageState{Cookies: storageState.Cookies, Origins: storageState.Origins}
+		}
+		var recordVideo *playwright.RecordVideo
+		if bc.Config.RecordVideoDir != "" {
+			recordVideo = &playwright.RecordVideo{Dir: bc.Config.RecordVideoDir, Size: bc.Config.RecordVideoSize}
+		}
+		bc.emulation, err = newContextEmulation(bc.Browser.Playwright.Devices, bc.Config, bc.Browser.Config.BrowserClass)
+		if err != nil {
+			return nil, err
+		}
 		context, err = browser.NewContext(
 			playwright.BrowserNewContextOptions{
-				NoViewport:        playwright.Bool(true),
-				UserAgent:         playwright.String(utils.GetDefaultValue(bc.Browser.Config, "user_agent", "")),
+				NoViewport:        bc.emulation.noViewport,
+				Viewport:          bc.emulation.viewport,
+				Screen:            bc.emulation.screen,
+				UserAgent:         bc.emulation.userAgent,
 				JavaScriptEnabled: playwright.Bool(true),
-				BypassCSP:         playwright.Bool(bc.Browser.Config["disable_security"].(bool)),
-				IgnoreHttpsErrors: playwright.Bool(bc.Browser.Config["disable_security"].(bool)),
-				// RecordVideo: &playwright.RecordVideo{
-				// 	Dir: bc.Browser.Config["save_recording_path"].(string),
-				// 	Size: &playwright.Size{
-				// 		Width:  bc.Browser.Config["browser_window_size"].(map[string]interface{})["width"].(int),
-				// 		Height: bc.Browser.Config["browser_window_size"].(map[string]interface{})["height"].(int),
-				// 	},
-				// },
-				// RecordHarPath:   playwright.String(bc.Browser.Config["save_har_path"].(string)),
-				Locale:          playwright.String(utils.GetDefaultValue(bc.Browser.Config, "locale", "")),
-				HttpCredentials: utils.GetDefaultValue[*playwright.HttpCredentials](bc.Browser.Config, "http_credentials", nil),
-				IsMobile:        playwright.Bool(utils.GetDefaultValue(bc.Browser.Config, "is_mobile", false)),
-				HasTouch:        playwright.Bool(bc.Browser.Config["has_touch"].(bool)),
-				// Geolocation: bc.Browser.Config["geolocation"].(*playwright.Geolocation),
-				// Permissions:     bc.Browser.Config["permissions"].([]string),
-				TimezoneId: playwright.String(utils.GetDefaultValue(bc.Browser.Config, "timezone_id", "")),
+				BypassCSP:         playwright.Bool(bc.Browser.Config.DisableSecurity),
+				IgnoreHttpsErrors: playwright.Bool(bc.Browser.Config.DisableSecurity),
+				RecordVideo:       recordVideo,
+				StorageState:      initialStorage,
+				RecordHarPath:     harPath,
+				RecordHarContent:  playwright.HarContentPolicyEmbed,
+				Locale:            optionalString(bc.Config.Locale),
+				HttpCredentials:   bc.Config.HttpCredentials,
+				IsMobile:          bc.emulation.isMobile,
+				HasTouch:          bc.emulation.hasTouch,
+				TimezoneId:        optionalString(bc.Config.TimezoneId),
+				Geolocation:       bc.Config.Geolocation,
+				Permissions:       bc.Config.Permissions,
+				ColorScheme:       bc.Config.colorScheme(),
+				ReducedMotion:     bc.Config.reducedMotion(),
+				ExtraHttpHeaders:  bc.Config.ExtraHttpHeaders,
+				Offline:           playwright.Bool(bc.Config.Offline),
+				DeviceScaleFactor: bc.emulation.deviceScaleFactor,
+				Proxy:             proxy,
 			},
 		)
 		if err != nil {
 			return nil, err
 		}
+		if harPath != nil {
+			bc.harPaths = append(bc.harPaths, *harPath)
+			log.Debugf("📼  Recording HAR to %s", *harPath)
+		}
+		if proxy != nil {
+			bc.proxy = proxy
+			log.Infof("🌐  Context %s uses proxy %s", bc.ContextId, proxy.Server)
+		}
+		if recordVideo != nil {
+			name := bc.videoName
+			if name == "" {
+				name = bc.ContextId
+			}
+			bc.videos = &videoRecorder{name: name}
+			bc.videos.attach(context)
+		}
+	}
+	if bc.Config.SaveHarPath != "" && len(bc.harPaths) == 0 {
+		log.Warn("⚠️ save_har_path is ignored for the existing context of a connected or persistent browser")
+	}
+	if (bc.Config.Device != "" || bc.Config.Viewport != nil) && bc.emulation == nil {
+		log.Warn("⚠️ device and viewport are ignored for the existing context of a connected browser")
+	}
+	if bc.Config.RecordVideoDir != "" && bc.videos == nil {
+		log.Warn("⚠️ record_video_dir is ignored for the existing context of a connected or persistent browser")
+	}
+	// a context created here is closed again if its setup fails, a reused one stays open
+	failSetup := func(err error) (playwright.BrowserContext, error) {
+		if !reused {
+			if closeErr := context.Close(); closeErr != nil {
+				log.Debugf("🪨  Failed to close browser context: %s", closeErr)
+			}
+			if harPath != nil {
+				bc.harPaths = bc.harPaths[:len(bc.harPaths)-1]
+				os.Remove(*harPath)
+			}
+			bc.videos = nil
+			bc.proxy = nil
+		}
+		return nil, err
+	}
+	if err := bc.replayHar(context); err != nil {
+		return failSetup(err)
+	}
+	if storageState != nil {
+		// a reused context was not created with the storage state
+		if err := restoreStorageState(context, storageState, reused, reused); err != nil {
+			return failSetup(fmt.Errorf("failed to restore storage state: %w", err))
+		}
 	}
 
 	bc.LoadCookies(context)
@@ -811,13 +1028,15 @@ func (bc *BrowserContext) createContext(browser playwright.Browser) (playwright.
             // Chrome runtime
             window.chrome = { runtime: {} };
 
-            // Permissions
-            const originalQuery = window.navigator.permissions.query;
-            window.navigator.permissions.query = (parameters) => (
-                parameters.name === 'notifications' ?
-                    Promise.resolve({ state: Notification.permission }) :
-                    originalQuery(parameters)
-            );
+            // Permissions (not available in every engine)
+            if (window.navigator.permissions) {
+                const originalQuery = window.navigator.permissions.query;
+                window.navigator.permissions.query = (parameters) => (
+                    parameters.name === 'notifications' ?
+                        Promise.resolve({ state: Notification.permission }) :
+                        originalQuery(parameters)
+                );
+            }
             (function () {
                 const originalAttachShadow = Element.prototype.attachShadow;
                 Element.prototype.attachShadow = function attachShadow(options) {
@@ -830,7 +1049,7 @@ func (bc *BrowserContext) createContext(browser playwright.Browser) (playwright.
 
 func (bc *BrowserContext) getCurrentPage(session *BrowserSession) playwright.Page {
 	pages := session.Context.Pages()
-	if bc.Browser.Config["cdp_url"] != nil && bc.State.TargetId != nil {
+	if bc.Browser.Config.CdpUrl != "" && bc.State.TargetId != nil {
 		targets := bc.getCdpTargets()
 		for _, target := range targets {
 			if target["targetId"] == *bc.State.TargetId {
@@ -910,7 +1129,7 @@ func (bc *BrowserContext) SwitchToTab(pageId int) error {
 	}
 
 	// Update target ID if using CDP
-	if bc.Browser.Config["cdp_url"] != nil {
+	if bc.Browser.Config.CdpUrl != "" {
 		targets := bc.getCdpTargets()
 		for _, target := range targets {
 			if target["url"] == page.URL() {
@@ -962,7 +1181,7 @@ func (bc *BrowserContext) CreateNewTab(url string) error {
 	}
 
 	// Get target ID for new page if using CDP
-	if cdpUrl, ok := bc.Browser.Config["cdp_url"].(string); ok && cdpUrl != "" {
+	if cdpUrl := bc.Browser.Config.CdpUrl; cdpUrl != "" {
 		targets := bc.getCdpTargets()
 		for _, target := range targets {
 			if targetUrl, ok := target["url"].(string); ok && targetUrl == newPage.URL() {
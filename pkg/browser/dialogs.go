@@ -0,0 +1,156 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Supported values of BrowserContextConfig.DialogPolicy
+const (
+	DialogPolicyAccept  = "accept"  // accept dialogs, answering prompts with dialog_prompt_text or their default value
+	DialogPolicyDismiss = "dismiss" // dismiss dialogs
+	DialogPolicyAsk     = "ask"     // let the dialog handler decide, the agent asks its model
+)
+
+// A javascript dialog (alert, confirm, prompt or beforeunload) opened by a page and how it was handled
+type DialogInfo struct {
+	Type         string `json:"type"`
+	Message      string `json:"message"`
+	DefaultValue string `json:"default_value,omitempty"`
+	Url          string `json:"url"`
+	Accepted     bool   `json:"accepted"`
+	PromptText   string `json:"prompt_text,omitempty"`
+}
+
+func (di *DialogInfo) String() string {
+	action := "dismissed"
+	if di.Accepted {
+		action = "accepted"
+		if di.Type == "prompt" {
+			action = fmt.Sprintf("answered with %q", di.PromptText)
+		}
+	}
+	return fmt.Sprintf("%s dialog %q on %s was %s", di.Type, di.Message, di.Url, action)
+}
+
+func DialogsToString(dialogs []*DialogInfo) string {
+	var dialogStrings []string
+	for _, dialog := range dialogs {
+		dialogStrings = append(dialogStrings, "- "+dialog.String())
+	}
+	return strings.Join(dialogStrings, "\n")
+}
+
+// Decision about a javascript dialog
+type DialogResponse struct {
+	Accept     bool
+	PromptText string // text for prompt dialogs, the default value of the prompt if empty
+}
+
+// Decides how to handle a dialog for the ask dialog policy
+type DialogHandler func(dialog DialogInfo) DialogResponse
+
+// Handles the javascript dialogs of all pages of a context and records them until they are reported
+type dialogRecorder struct {
+	mu       sync.Mutex
+	config   *BrowserContextConfig
+	handler  DialogHandler
+	recorded []*DialogInfo
+}
+
+// Handle the dialogs of all pages of the context
+func (dr *dialogRecorder) attach(context playwright.BrowserContext) {
+	context.OnDialog(dr.onDialog)
+}
+
+func (dr *dialogRecorder) onDialog(dialog playwright.Dialog) {
+	info := DialogInfo{
+		Type:         dialog.Type(),
+		Message:      dialog.Message(),
+		DefaultValue: dialog.DefaultValue(),
+	}
+	if page := dialog.Page(); page != nil {
+		info.Url = page.URL()
+	}
+	dr.handle(dialog, info)
+}
+
+func (dr *dialogRecorder) decide(info DialogInfo) DialogResponse {
+	switch dr.config.DialogPolicy {
+	case DialogPolicyDismiss:
+		return DialogResponse{Accept: false}
+	case DialogPolicyAsk:
+		dr.mu.Lock()
+		handler := dr.handler
+		dr.mu.Unlock()
+		if handler != nil {
+			return handler(info)
+		}
+		log.Warnf("⚠️ No dialog handler set for dialog_policy %q, dismissing the dialog", DialogPolicyAsk)
+		return DialogResponse{Accept: false}
+	default:
+		return DialogResponse{Accept: true, PromptText: dr.config.DialogPromptText}
+	}
+}
+
+func (dr *dialogRecorder) handle(dialog playwright.Dialog, info DialogInfo) {
+	response := dr.decide(info)
+	var err error
+	if response.Accept {
+		info.Accepted = true
+		if info.Type == "prompt" {
+			info.PromptText = response.PromptText
+			if info.PromptText == "" {
+				info.PromptText = info.DefaultValue
+			}
+			err = dialog.Accept(info.PromptText)
+		} else {
+			err = dialog.Accept()
+		}
+	} else {
+		err = dialog.Dismiss()
+	}
+	if err != nil {
+		log.Debugf("🪨  Failed to handle %s dialog: %s", info.Type, err)
+	}
+	log.Infof("💬  %s", info.String())
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.recorded = append(dr.recorded, &info)
+}
+
+// Take the dialogs recorded since the last call
+func (dr *dialogRecorder) take() []*DialogInfo {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dialogs := dr.recorded
+	dr.recorded = nil
+	if dialogs == nil {
+		return []*DialogInfo{}
+	}
+	return dialogs
+}
+
+// The dialogs recorded since the last take, which are kept for the next take
+func (dr *dialogRecorder) peek() []*DialogInfo {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dialogs := make([]*DialogInfo, 0, len(dr.recorded))
+	for _, info := range dr.recorded {
+		dialog := *info
+		dialogs = append(dialogs, &dialog)
+	}
+	return dialogs
+}
+
+// Set the handler deciding about dialogs for the ask dialog policy
+func (bc *BrowserContext) SetDialogHandler(handler DialogHandler) {
+	bc.dialogs.mu.Lock()
+	defer bc.dialogs.mu.Unlock()
+	bc.dialogs.handler = handler
+}
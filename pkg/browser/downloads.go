@@ -0,0 +1,231 @@
+package browser
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+type DownloadStatus string
+
+const (
+	DownloadInProgress DownloadStatus = "in_progress"
+	DownloadCompleted  DownloadStatus = "completed"
+	DownloadFailed     DownloadStatus = "failed"
+)
+
+// A file downloaded by any page of the browser context
+type DownloadInfo struct {
+	Url               string         `json:"url"`
+	SuggestedFilename string         `json:"suggested_filename"`
+	Path              string         `json:"path"`
+	Status            DownloadStatus `json:"status"`
+	Size              int64          `json:"size"`
+	MimeType          string         `json:"mime_type"`
+	Error             string         `json:"error,omitempty"`
+	StartedAt         time.Time      `json:"started_at"`
+}
+
+func (di *DownloadInfo) String() string {
+	switch di.Status {
+	case DownloadCompleted:
+		return fmt.Sprintf("%s (%s, %d bytes)", di.Path, di.MimeType, di.Size)
+	case DownloadFailed:
+		return fmt.Sprintf("%s (failed: %s)", di.SuggestedFilename, di.Error)
+	default:
+		return fmt.Sprintf("%s (downloading)", di.SuggestedFilename)
+	}
+}
+
+func DownloadsToString(downloads []*DownloadInfo) string {
+	var downloadStrings []string
+	for _, download := range downloads {
+		downloadStrings = append(downloadStrings, "- "+download.String())
+	}
+	return strings.Join(downloadStrings, "\n")
+}
+
+type trackedDownload struct {
+	info *DownloadInfo
+	done chan struct{}
+}
+
+// How long a click waits for the download it started, longer downloads are reported as in progress
+var DOWNLOAD_WAIT_TIMEOUT = 30 * time.Second
+
+// Saves the downloads of all pages of a context to a directory and keeps track of them
+type downloadManager struct {
+	mu        sync.Mutex
+	directory string
+	temporary bool // the directory was created for the context and is removed on cleanup
+	downloads map[playwright.Download]*trackedDownload
+	order     []*trackedDownload
+	// reserved file names of downloads in progress, so concurrent downloads get unique names
+	reserved map[string]struct{}
+}
+
+// Create a manager saving to directory, or to a new temporary directory if it is empty
+func newDownloadManager(directory string) (*downloadManager, error) {
+	temporary := false
+	if directory == "" {
+		var err error
+		if directory, err = os.MkdirTemp("", "browser-use-downloads-"); err != nil {
+			return nil, fmt.Errorf("failed to create a downloads directory: %w", err)
+		}
+		temporary = true
+	}
+	return &downloadManager{
+		directory: directory,
+		temporary: temporary,
+		downloads: make(map[playwright.Download]*trackedDownload),
+		reserved:  make(map[string]struct{}),
+	}, nil
+}
+
+// Remove the temporary directory with the downloaded files, the list of downloads is kept
+func (dm *downloadManager) cleanup() {
+	if !dm.temporary {
+		return
+	}
+	if err := os.RemoveAll(dm.directory); err != nil {
+		log.Warnf("⚠️ Failed to remove temporary downloads directory %s: %s", dm.directory, err)
+	}
+}
+
+// Track the downloads of all current and future pages of the context
+func (dm *downloadManager) attach(context playwright.BrowserContext) {
+	for _, page := range context.Pages() {
+		page.OnDownload(dm.onDownload)
+	}
+	context.OnPage(func(page playwright.Page) {
+		page.OnDownload(dm.onDownload)
+	})
+}
+
+func (dm *downloadManager) onDownload(download playwright.Download) {
+	dm.track(download)
+}
+
+// Start saving a download, tracking the same download twice returns the first tracking
+func (dm *downloadManager) track(download playwright.Download) *trackedDownload {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if tracked, ok := dm.downloads[download]; ok {
+		return tracked
+	}
+
+	filename := uniqueFilename(dm.directory, download.SuggestedFilename(), dm.reserved)
+	dm.reserved[filename] = struct{}{}
+	tracked := &trackedDownload{
+		info: &DownloadInfo{
+			Url:               download.URL(),
+			SuggestedFilename: download.SuggestedFilename(),
+			Path:              filepath.Join(dm.directory, filename),
+			Status:            DownloadInProgress,
+			StartedAt:         time.Now(),
+		},
+		done: make(chan struct{}),
+	}
+	dm.downloads[download] = tracked
+	dm.order = append(dm.order, tracked)
+
+	// saving blocks until the download has finished, which must not happen in the event handler
+	go dm.save(download, tracked, filename)
+	return tracked
+}
+
+func (dm *downloadManager) save(download playwright.Download, tracked *trackedDownload, filename string) {
+	defer close(tracked.done)
+	path := tracked.info.Path
+	err := os.MkdirAll(dm.directory, 0755)
+	if err == nil {
+		err = download.SaveAs(path)
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	delete(dm.reserved, filename)
+	if err != nil {
+		log.Warnf("❌  Failed to save download %s: %s", tracked.info.SuggestedFilename, err)
+		tracked.info.Status = DownloadFailed
+		tracked.info.Error = err.Error()
+		return
+	}
+	if stat, err := os.Stat(path); err == nil {
+		tracked.info.Size = stat.Size()
+	}
+	tracked.info.MimeType = detectMimeType(path)
+	tracked.info.Status = DownloadCompleted
+	log.Debugf("⬇️  Download finished. Saved file to: %s", path)
+}
+
+// Generate a unique filename by appending (1), (2), etc., if a file already exists or the name is reserved.
+func uniqueFilename(directory, filename string, reserved map[string]struct{}) string {
+	filename = filepath.Base(filename)
+	if filename == "" || filename == "." || filename == string(filepath.Separator) {
+		filename = "download"
+	}
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	newFilename := filename
+	counter := 1
+
+	for {
+		fullPath := filepath.Join(directory, newFilename)
+		_, isReserved := reserved[newFilename]
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) && !isReserved {
+			break
+		}
+		newFilename = fmt.Sprintf("%s (%d)%s", base, counter, ext)
+		counter++
+	}
+	return newFilename
+}
+
+// Wait until the download is saved or failed, at most timeout. A download still running is returned in progress.
+func (dm *downloadManager) wait(download playwright.Download, timeout time.Duration) DownloadInfo {
+	tracked := dm.track(download)
+	select {
+	case <-tracked.done:
+	case <-time.After(timeout):
+		log.Infof("⬇️  Download of %s is still in progress after %s", tracked.info.SuggestedFilename, timeout)
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return *tracked.info
+}
+
+// Snapshot of all downloads in the order they started
+func (dm *downloadManager) list() []*DownloadInfo {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	downloads := make([]*DownloadInfo, 0, len(dm.order))
+	for _, tracked := range dm.order {
+		info := *tracked.info
+		downloads = append(downloads, &info)
+	}
+	return downloads
+}
+
+// Detect the mime type from the file extension, or from the content if the extension is unknown
+func detectMimeType(path string) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		return mimeType
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	return http.DetectContentType(buf[:n])
+}
@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Download whose file is written once release is closed
+type fakeDownload struct {
+	playwright.Download
+	release chan struct{}
+}
+
+func (d *fakeDownload) URL() string               { return "https://example.com/report.csv" }
+func (d *fakeDownload) SuggestedFilename() string { return "report.csv" }
+func (d *fakeDownload) SaveAs(path string) error {
+	<-d.release
+	return os.WriteFile(path, []byte("a,b\n"), 0644)
+}
+
+func TestDownloadManagerWaitTimeout(t *testing.T) {
+	dm, err := newDownloadManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dm.temporary {
+		t.Fatal("expected a temporary directory without save_downloads_path")
+	}
+	download := &fakeDownload{release: make(chan struct{})}
+
+	info := dm.wait(download, 10*time.Millisecond)
+	if info.Status != DownloadInProgress {
+		t.Fatalf("expected the download in progress after the timeout, got %s", info.Status)
+	}
+	if filepath.Dir(info.Path) != dm.directory {
+		t.Fatalf("expected the download in %s, got %s", dm.directory, info.Path)
+	}
+
+	close(download.release)
+	info = dm.wait(download, time.Second)
+	if info.Status != DownloadCompleted || info.Size != 4 {
+		t.Fatalf("expected the completed download, got %+v", info)
+	}
+
+	dm.cleanup()
+	if _, err := os.Stat(dm.directory); !os.IsNotExist(err) {
+		t.Fatalf("expected the temporary directory to be removed, got %v", err)
+	}
+	if downloads := dm.list(); len(downloads) != 1 || downloads[0].Status != DownloadCompleted {
+		t.Fatalf("expected the download to stay listed after cleanup, got %v", downloads)
+	}
+}
+
+func TestDownloadManagerKeepsConfiguredDirectory(t *testing.T) {
+	directory := t.TempDir()
+	dm, err := newDownloadManager(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm.cleanup()
+	if _, err := os.Stat(directory); err != nil {
+		t.Fatalf("expected save_downloads_path to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Supported values of BrowserContextConfig.ColorScheme
+const (
+	ColorSchemeLight        = "light"
+	ColorSchemeDark         = "dark"
+	ColorSchemeNoPreference = "no-preference"
+)
+
+// Supported values of BrowserContextConfig.ReducedMotion
+const (
+	ReducedMotionReduce       = "reduce"
+	ReducedMotionNoPreference = "no-preference"
+)
+
+// Validate the emulation options of the context configuration
+func (c *BrowserContextConfig) validateEmulation() []error {
+	var errs []error
+	colorSchemes := []string{ColorSchemeLight, ColorSchemeDark, ColorSchemeNoPreference}
+	if c.ColorScheme != "" && !slices.Contains(colorSchemes, c.ColorScheme) {
+		errs = append(errs, fmt.Errorf("color_scheme must be one of %s, got %q", strings.Join(colorSchemes, ", "), c.ColorScheme))
+	}
+	reducedMotions := []string{ReducedMotionReduce, ReducedMotionNoPreference}
+	if c.ReducedMotion != "" && !slices.Contains(reducedMotions, c.ReducedMotion) {
+		errs = append(errs, fmt.Errorf("reduced_motion must be one of %s, got %q", strings.Join(reducedMotions, ", "), c.ReducedMotion))
+	}
+	if c.Geolocation != nil {
+		if c.Geolocation.Latitude < -90 || c.Geolocation.Latitude > 90 {
+			errs = append(errs, fmt.Errorf("geolocation.latitude must be between -90 and 90, got %g", c.Geolocation.Latitude))
+		}
+		if c.Geolocation.Longitude < -180 || c.Geolocation.Longitude > 180 {
+			errs = append(errs, fmt.Errorf("geolocation.longitude must be between -180 and 180, got %g", c.Geolocation.Longitude))
+		}
+		if c.Geolocation.Accuracy != nil && *c.Geolocation.Accuracy < 0 {
+			errs = append(errs, errors.New("geolocation.accuracy must not be negative"))
+		}
+	}
+	if slices.Contains(c.Permissions, "") {
+		errs = append(errs, errors.New("permissions must not contain empty names"))
+	}
+	if c.DeviceScaleFactor < 0 {
+		errs = append(errs, errors.New("device_scale_factor must not be negative"))
+	}
+	return errs
+}
+
+func (c *BrowserContextConfig) colorScheme() *playwright.ColorScheme {
+	if c.ColorScheme == "" {
+		return nil
+	}
+	colorScheme := playwright.ColorScheme(c.ColorScheme)
+	return &colorScheme
+}
+
+func (c *BrowserContextConfig) reducedMotion() *playwright.ReducedMotion {
+	if c.ReducedMotion == "" {
+		return nil
+	}
+	reducedMotion := playwright.ReducedMotion(c.ReducedMotion)
+	return &reducedMotion
+}
+
+// Viewport, screen and device options of a context, from the device preset and the explicit options of the config
+type contextEmulation struct {
+	userAgent         *string
+	viewport          *playwright.Size
+	screen            *playwright.Size
+	noViewport        *bool
+	deviceScaleFactor *float64
+	isMobile          *bool
+	hasTouch          *bool
+	mobile            bool // whether pages are emulated as mobile, reported in the browser state
+}
+
+// Resolve the emulation options of a config, explicit options take precedence over the device preset
+func newContextEmulation(devices map[string]*playwright.DeviceDescriptor, config *BrowserContextConfig, browserClass string) (*contextEmulation, error) {
+	device := &playwright.DeviceDescriptor{}
+	if config.Device != "" {
+		var ok bool
+		if device, ok = devices[config.Device]; !ok {
+			return nil, unknownDeviceError(config.Device, devices)
+		}
+	}
+	emulation := &contextEmulation{
+		userAgent: optionalString(config.UserAgent),
+		viewport:  config.Viewport,
+		screen:    device.Screen,
+		mobile:    device.IsMobile,
+		hasTouch:  playwright.Bool(device.HasTouch),
+	}
+	if config.IsMobile != nil {
+		emulation.mobile = *config.IsMobile
+	}
+	if config.HasTouch != nil {
+		emulation.hasTouch = playwright.Bool(*config.HasTouch)
+	}
+	if emulation.userAgent == nil {
+		emulation.userAgent = optionalString(device.UserAgent)
+	}
+	if emulation.viewport == nil {
+		emulation.viewport = device.Viewport
+	}
+	if config.DeviceScaleFactor > 0 {
+		emulation.deviceScaleFactor = playwright.Float(config.DeviceScaleFactor)
+	} else if device.DeviceScaleFactor > 0 {
+		emulation.deviceScaleFactor = playwright.Float(device.DeviceScaleFactor)
+	}
+	// pages size to the browser window without a viewport, playwright's default 1280x720 viewport is used
+	// for a scale factor as it is not supported without one
+	if emulation.viewport == nil {
+		emulation.noViewport = playwright.Bool(emulation.deviceScaleFactor == nil)
+	}
+	// firefox rejects the is_mobile option altogether
+	if browserClass != BrowserClassFirefox {
+		emulation.isMobile = playwright.Bool(emulation.mobile)
+	} else if emulation.mobile {
+		log.Warn("⚠️ is_mobile is not supported by firefox, ignoring it")
+		emulation.mobile = false
+	}
+	return emulation, nil
+}
+
+func unknownDeviceError(name string, devices map[string]*playwright.DeviceDescriptor) error {
+	suggestion := ""
+	bestDistance := 4
+	for deviceName := range devices {
+		if distance := levenshtein(strings.ToLower(name), strings.ToLower(deviceName)); distance < bestDistance || (distance == bestDistance && deviceName < suggestion) {
+			suggestion = deviceName
+			bestDistance = distance
+		}
+	}
+	if suggestion != "" {
+		return fmt.Errorf("unknown device %q, did you mean %q?", name, suggestion)
+	}
+	return fmt.Errorf("unknown device %q, see playwright's device descriptors for the supported names", name)
+}
+
+// Size of the current page as the agent sees it
+func (bc *BrowserContext) getViewportInfo(page playwright.Page) (*ViewportInfo, error) {
+	result, err := page.Evaluate(`() => ({ width: window.innerWidth, height: window.innerHeight, device_scale_factor: window.devicePixelRatio })`)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected viewport %v", result)
+	}
+	width, err := ParseNumberToInt(values["width"])
+	if err != nil {
+		return nil, err
+	}
+	height, err := ParseNumberToInt(values["height"])
+	if err != nil {
+		return nil, err
+	}
+	deviceScaleFactor, err := ParseNumberToFloat(values["device_scale_factor"])
+	if err != nil {
+		return nil, err
+	}
+	viewport := &ViewportInfo{Width: width, Height: height, DeviceScaleFactor: deviceScaleFactor}
+	if bc.emulation != nil {
+		viewport.IsMobile = bc.emulation.mobile
+	}
+	return viewport, nil
+}
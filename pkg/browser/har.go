@@ -0,0 +1,53 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Supported values of BrowserContextConfig.ReplayHarNotFound
+const (
+	HarNotFoundAbort    = "abort"    // abort requests missing from the HAR, nothing reaches the network
+	HarNotFoundFallback = "fallback" // send requests missing from the HAR to the network
+)
+
+// Path for the HAR of a new session in save_har_path, nil if HAR recording is off.
+// The file is written by playwright when the context is closed, the path is added to HarPaths once the context is created.
+func (bc *BrowserContext) newHarPath() (*string, error) {
+	if bc.Config.SaveHarPath == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(bc.Config.SaveHarPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create save_har_path: %w", err)
+	}
+	path := filepath.Join(bc.Config.SaveHarPath, uniqueFilename(bc.Config.SaveHarPath, bc.ContextId+".har", nil))
+	return &path, nil
+}
+
+// Serve the requests of the context from replay_har_path
+func (bc *BrowserContext) replayHar(context playwright.BrowserContext) error {
+	if bc.Config.ReplayHarPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(bc.Config.ReplayHarPath); err != nil {
+		return fmt.Errorf("failed to open replay_har_path: %w", err)
+	}
+	notFound := playwright.HarNotFoundAbort
+	if bc.Config.ReplayHarNotFound == HarNotFoundFallback {
+		notFound = playwright.HarNotFoundFallback
+	}
+	if err := context.RouteFromHAR(bc.Config.ReplayHarPath, playwright.BrowserContextRouteFromHAROptions{NotFound: notFound}); err != nil {
+		return fmt.Errorf("failed to replay HAR %s: %w", bc.Config.ReplayHarPath, err)
+	}
+	log.Debugf("📼  Replaying requests from %s", bc.Config.ReplayHarPath)
+	return nil
+}
+
+// Paths of the HAR files recorded by the sessions of this context, complete once the context is closed
+func (bc *BrowserContext) HarPaths() []string {
+	return bc.harPaths
+}
@@ -0,0 +1,205 @@
+package browser
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Resource types that have to finish before a page counts as loaded
+var RELEVANT_RESOURCE_TYPES = []string{
+	"document",
+	"stylesheet",
+	"image",
+	"font",
+	"script",
+	"iframe",
+	"fetch",
+	"xhr",
+}
+
+// Requests to urls containing one of these patterns never finish loading or do not affect the page
+var IGNORED_URL_PATTERNS = []string{
+	// Analytics and tracking
+	"analytics",
+	"tracking",
+	"telemetry",
+	"beacon",
+	"metrics",
+	// Ad-related
+	"doubleclick",
+	"adsystem",
+	"adserver",
+	"advertising",
+	// Social media widgets
+	"facebook.com/plugins",
+	"platform.twitter",
+	"linkedin.com/embed",
+	// Live chat and support
+	"livechat",
+	"zendesk",
+	"intercom",
+	"crisp.chat",
+	"hotjar",
+	// Push notifications
+	"push-notifications",
+	"onesignal",
+	"pushwoosh",
+	// Background sync/heartbeat
+	"heartbeat",
+	"ping",
+	"alive",
+	// WebRTC and streaming
+	"webrtc",
+	"rtmp://",
+	"wss://",
+	// Common CDNs for dynamic content
+	"cloudfront.net",
+	"fastly.net",
+}
+
+// Check if a request has to finish before the page counts as loaded
+func isRelevantRequest(url string, resourceType string, headers map[string]string) bool {
+	if !slices.Contains(RELEVANT_RESOURCE_TYPES, resourceType) {
+		return false
+	}
+	if strings.HasPrefix(url, "data:") || strings.HasPrefix(url, "blob:") {
+		return false
+	}
+	lowerUrl := strings.ToLower(url)
+	for _, pattern := range IGNORED_URL_PATTERNS {
+		if strings.Contains(lowerUrl, pattern) {
+			return false
+		}
+	}
+	// streaming and prefetch requests may stay open for a long time
+	if strings.Contains(headers["accept"], "text/event-stream") {
+		return false
+	}
+	if headers["purpose"] == "prefetch" || strings.HasPrefix(headers["sec-purpose"], "prefetch") {
+		return false
+	}
+	return true
+}
+
+// Tracks the relevant requests of the pages of a context that have not finished yet
+type networkTracker struct {
+	mu           sync.Mutex
+	pending      map[playwright.Request]playwright.Page
+	lastActivity map[playwright.Page]time.Time
+}
+
+func newNetworkTracker() *networkTracker {
+	return &networkTracker{
+		pending:      make(map[playwright.Request]playwright.Page),
+		lastActivity: make(map[playwright.Page]time.Time),
+	}
+}
+
+// Track the requests of all pages of the context
+func (nt *networkTracker) attach(context playwright.BrowserContext) {
+	context.OnRequest(nt.onRequest)
+	context.OnResponse(nt.onResponse)
+	context.OnRequestFailed(nt.onRequestDone)
+	for _, page := range context.Pages() {
+		page.OnClose(nt.onPageClose)
+	}
+	context.OnPage(func(page playwright.Page) {
+		page.OnClose(nt.onPageClose)
+	})
+}
+
+func requestPage(request playwright.Request) playwright.Page {
+	frame := request.Frame()
+	if frame == nil {
+		return nil
+	}
+	return frame.Page()
+}
+
+func (nt *networkTracker) onRequest(request playwright.Request) {
+	if !isRelevantRequest(request.URL(), request.ResourceType(), request.Headers()) {
+		return
+	}
+	page := requestPage(request)
+	if page == nil {
+		return
+	}
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	nt.pending[request] = page
+	nt.lastActivity[page] = time.Now()
+}
+
+func (nt *networkTracker) onRequestDone(request playwright.Request) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	page, ok := nt.pending[request]
+	if !ok {
+		return
+	}
+	delete(nt.pending, request)
+	nt.lastActivity[page] = time.Now()
+}
+
+// Forget a closed page, its requests will not finish anymore
+func (nt *networkTracker) onPageClose(page playwright.Page) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	delete(nt.lastActivity, page)
+	for request, p := range nt.pending {
+		if p == page {
+			delete(nt.pending, request)
+		}
+	}
+}
+
+func (nt *networkTracker) onResponse(response playwright.Response) {
+	nt.onRequestDone(response.Request())
+}
+
+// Number of pending requests of the page and the time since one of them started or finished
+func (nt *networkTracker) status(page playwright.Page) (int, time.Duration) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	pending := 0
+	for _, p := range nt.pending {
+		if p == page {
+			pending++
+		}
+	}
+	lastActivity, ok := nt.lastActivity[page]
+	if !ok {
+		// no relevant request seen yet
+		return pending, time.Duration(math.MaxInt64)
+	}
+	return pending, time.Since(lastActivity)
+}
+
+// Wait until the relevant requests of the page have finished and the network was idle for
+// wait_for_network_idle_page_load_time, at most maximum_wait_page_load_time.
+func (bc *BrowserContext) waitForStableNetwork(page playwright.Page) {
+	if bc.network == nil {
+		return
+	}
+	idleTime := seconds(bc.Config.WaitForNetworkIdlePageLoadTime)
+	maxTime := seconds(bc.Config.MaximumWaitPageLoadTime)
+	startTime := time.Now()
+	for {
+		pending, idle := bc.network.status(page)
+		if pending == 0 && idle >= idleTime {
+			log.Debugf("⚖️  Network stabilized for %s", idleTime)
+			return
+		}
+		if time.Since(startTime) > maxTime {
+			log.Debugf("🪨  Network timeout after %s with %d pending requests", maxTime, pending)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package browser
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCloseStopsChromeProcessGroup(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "chrome-profile-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBrowserWithSettings(nil)
+	b.tempUserDataDir = tempDir
+	// the child ignores SIGTERM and has to be killed with the group
+	cmd := exec.Command("sh", "-c", "(trap '' TERM; sleep 60) & wait")
+	if err := b.startChromeProcess(cmd); err != nil {
+		t.Fatal(err)
+	}
+	pid := cmd.Process.Pid
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// killed children are reaped asynchronously by init
+	err = nil
+	for i := 0; i < 50; i++ {
+		if err = syscall.Kill(-pid, 0); errors.Is(err, syscall.ESRCH) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !errors.Is(err, syscall.ESRCH) {
+		t.Errorf("expected process group %d to be gone, got %v", pid, err)
+	}
+	if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+		t.Errorf("expected temporary user data dir to be removed, got %v", err)
+	}
+	if b.chromeProcess != nil || b.tempUserDataDir != "" {
+		t.Error("browser should not reference the stopped process")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package browser
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Process groups are not used on windows, chrome stops its helper processes itself
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func terminateProcessGroup(p *os.Process) error {
+	return killProcessGroup(p)
+}
+
+func killProcessGroup(p *os.Process) error {
+	err := p.Kill()
+	if errors.Is(err, os.ErrProcessDone) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,289 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile does not exist")
+	ErrProfileExists   = errors.New("profile already exists")
+)
+
+// The OS lock of a lock file is held by another open file
+var errLockHeld = errors.New("lock is held")
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Files chrome keeps in a running profile, they must not be copied to a clone
+var CHROME_SINGLETON_FILES = []string{"SingletonLock", "SingletonSocket", "SingletonCookie", DEVTOOLS_ACTIVE_PORT_FILE}
+
+// Manages named browser profiles (user data directories) under a root directory.
+// A profile is used by one browser at a time, Browser.Start locks the profile of the config.
+type ProfileManager struct {
+	Root string
+}
+
+type ProfileInfo struct {
+	Name       string
+	Path       string // the user data directory
+	Locked     bool
+	ModifiedAt time.Time
+}
+
+// A held profile lock, released with Unlock
+type ProfileLock struct {
+	file *os.File
+}
+
+// Create a manager for the profiles in root, creating root if needed
+func NewProfileManager(root string) (*ProfileManager, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &ProfileManager{Root: root}, nil
+}
+
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// The user data directory of the profile
+func (pm *ProfileManager) Path(name string) string {
+	return filepath.Join(pm.Root, name)
+}
+
+func (pm *ProfileManager) lockPath(name string) string {
+	return filepath.Join(pm.Root, name+".lock")
+}
+
+func (pm *ProfileManager) exists(name string) bool {
+	stat, err := os.Stat(pm.Path(name))
+	return err == nil && stat.IsDir()
+}
+
+// Create an empty profile
+func (pm *ProfileManager) Create(name string) (*ProfileInfo, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(pm.Path(name), 0700); err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrProfileExists, name)
+		}
+		return nil, err
+	}
+	return pm.Get(name)
+}
+
+// Get a profile, creating it if it does not exist yet
+func (pm *ProfileManager) Ensure(name string) (*ProfileInfo, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(pm.Path(name), 0700); err != nil {
+		return nil, err
+	}
+	return pm.Get(name)
+}
+
+func (pm *ProfileManager) Get(name string) (*ProfileInfo, error) {
+	stat, err := os.Stat(pm.Path(name))
+	if err != nil || !stat.IsDir() || validateProfileName(name) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	return &ProfileInfo{
+		Name:       name,
+		Path:       pm.Path(name),
+		Locked:     pm.isLocked(name),
+		ModifiedAt: stat.ModTime(),
+	}, nil
+}
+
+// All profiles sorted by name
+func (pm *ProfileManager) List() ([]*ProfileInfo, error) {
+	entries, err := os.ReadDir(pm.Root)
+	if err != nil {
+		return nil, err
+	}
+	profiles := []*ProfileInfo{}
+	for _, entry := range entries {
+		if !entry.IsDir() || validateProfileName(entry.Name()) != nil {
+			continue
+		}
+		if profile, err := pm.Get(entry.Name()); err == nil {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+// Copy a profile, e.g. to start several identities from one logged in profile.
+// The source is locked while it is copied, so it must not be in use.
+func (pm *ProfileManager) Clone(source string, target string) (*ProfileInfo, error) {
+	if err := validateProfileName(target); err != nil {
+		return nil, err
+	}
+	if !pm.exists(source) {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, source)
+	}
+	lock, err := pm.Lock(source)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	// creating the target first makes concurrent clones to the same name fail instead of mixing their files
+	if err := os.Mkdir(pm.Path(target), 0700); err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrProfileExists, target)
+		}
+		return nil, err
+	}
+	if err := copyProfileDir(pm.Path(source), pm.Path(target)); err != nil {
+		os.RemoveAll(pm.Path(target))
+		return nil, fmt.Errorf("failed to clone profile %s: %w", source, err)
+	}
+	return pm.Get(target)
+}
+
+func copyProfileDir(source string, target string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		for _, name := range CHROME_SINGLETON_FILES {
+			if d.Name() == name {
+				return nil
+			}
+		}
+		targetPath := filepath.Join(target, rel)
+		if d.IsDir() {
+			return os.MkdirAll(targetPath, 0700)
+		}
+		if !d.Type().IsRegular() {
+			return nil // sockets and links of a running browser
+		}
+		return copyFile(path, targetPath)
+	})
+}
+
+func copyFile(source string, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Delete a profile that is not in use
+func (pm *ProfileManager) Delete(name string) error {
+	if !pm.exists(name) {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	lock, err := pm.Lock(name)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return os.RemoveAll(pm.Path(name))
+}
+
+// Whether the lock of the profile is held, also by the current process
+func (pm *ProfileManager) isLocked(name string) bool {
+	file, err := os.OpenFile(pm.lockPath(name), os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	if err := lockFile(file); err != nil {
+		return errors.Is(err, errLockHeld)
+	}
+	unlockFile(file)
+	return false
+}
+
+// Pid written to the lock file by the holder of the lock, 0 if unknown
+func (pm *ProfileManager) lockHolder(name string) int {
+	data, err := os.ReadFile(pm.lockPath(name))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// Lock a profile. Fails with ErrProfileLocked while the lock is held, also by the current process.
+// The lock is an OS lock on the lock file, it is released by the OS when the holding process is gone.
+func (pm *ProfileManager) Lock(name string) (*ProfileLock, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(pm.lockPath(name), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if !errors.Is(err, errLockHeld) {
+			return nil, err
+		}
+		if pid := pm.lockHolder(name); pid != 0 {
+			return nil, fmt.Errorf("%w: %s is used by process %d", ErrProfileLocked, name, pid)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrProfileLocked, name)
+	}
+	// the pid is informational, the OS lock decides who holds the profile
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	if err != nil {
+		unlockFile(file)
+		file.Close()
+		return nil, err
+	}
+	return &ProfileLock{file: file}, nil
+}
+
+// Release the lock. The lock file is kept, removing it would let a process that opened it
+// before the removal lock a file no one else sees.
+func (l *ProfileLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
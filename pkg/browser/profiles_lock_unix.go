@@ -0,0 +1,22 @@
+//go:build !windows
+
+package browser
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Take the exclusive lock of the file without waiting, fails with errLockHeld while another open file holds it
+func lockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
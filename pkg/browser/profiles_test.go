@@ -0,0 +1,175 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProfileManager(t *testing.T) {
+	pm, err := NewProfileManager(filepath.Join(t.TempDir(), "profiles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Create("customer-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Create("customer-a"); !errors.Is(err, ErrProfileExists) {
+		t.Errorf("expected ErrProfileExists, got %v", err)
+	}
+	if _, err := pm.Create("../escape"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+	os.WriteFile(filepath.Join(pm.Path("customer-a"), "Cookies"), []byte("cookies"), 0600)
+	os.WriteFile(filepath.Join(pm.Path("customer-a"), "SingletonLock"), []byte("host-1"), 0600)
+
+	clone, err := pm.Clone("customer-a", "customer-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(clone.Path, "Cookies")); string(data) != "cookies" {
+		t.Errorf("expected the profile files to be copied, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(clone.Path, "SingletonLock")); !os.IsNotExist(err) {
+		t.Error("chrome singleton files should not be copied")
+	}
+
+	lock, err := pm.Lock("customer-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Lock("customer-a"); !errors.Is(err, ErrProfileLocked) {
+		t.Errorf("expected ErrProfileLocked, got %v", err)
+	}
+	if err := pm.Delete("customer-a"); !errors.Is(err, ErrProfileLocked) {
+		t.Errorf("a locked profile should not be deleted, got %v", err)
+	}
+	profiles, err := pm.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "customer-a" || !profiles[0].Locked || profiles[1].Locked {
+		t.Errorf("unexpected profiles: %+v", profiles)
+	}
+	lock.Unlock()
+
+	if err := pm.Delete("customer-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Get("customer-a"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestProfileManagerStaleLock(t *testing.T) {
+	pm, err := NewProfileManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pids are far below this on all supported systems
+	os.WriteFile(pm.lockPath("stale"), []byte(strconv.Itoa(1<<30)), 0644)
+	lock, err := pm.Lock("stale")
+	if err != nil {
+		t.Fatalf("a lock of a process that is gone should be taken over: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestBrowserStartProfileLocked(t *testing.T) {
+	dir := t.TempDir()
+	pm, _ := NewProfileManager(dir)
+	lock, err := pm.Lock("busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true), WithProfile(dir, "busy")))
+	defer browser.Close()
+	if err := browser.Start(context.Background()); !errors.Is(err, ErrProfileLocked) {
+		t.Errorf("expected ErrProfileLocked, got %v", err)
+	}
+}
+
+func TestProfileLockRelock(t *testing.T) {
+	pm, err := NewProfileManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pm.Create("reused"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		lock, err := pm.Lock("reused")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(pm.lockPath("reused")); string(data) != strconv.Itoa(os.Getpid()) {
+			t.Errorf("expected the pid in the lock file, got %q", data)
+		}
+		if _, err := pm.Lock("reused"); !errors.Is(err, ErrProfileLocked) || !strings.Contains(err.Error(), strconv.Itoa(os.Getpid())) {
+			t.Errorf("expected ErrProfileLocked naming the holder, got %v", err)
+		}
+		if err := lock.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+		if profile, _ := pm.Get("reused"); profile.Locked {
+			t.Error("expected the profile to be unlocked")
+		}
+	}
+}
+
+func TestBrowserStartDriverErrorUnlocksProfile(t *testing.T) {
+	t.Setenv("PLAYWRIGHT_DRIVER_PATH", t.TempDir())
+	dir := t.TempDir()
+
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithHeadless(true), WithProfile(dir, "unlocked")))
+	if err := browser.Start(context.Background()); !errors.Is(err, ErrDriverNotInstalled) {
+		t.Fatalf("expected ErrDriverNotInstalled, got %v", err)
+	}
+	pm, _ := NewProfileManager(dir)
+	if profile, err := pm.Get("unlocked"); err != nil || profile.Locked {
+		t.Errorf("expected the profile to be unlocked after a failed start, got %+v, %v", profile, err)
+	}
+}
+
+func TestProfileManagerConcurrentClones(t *testing.T) {
+	pm, err := NewProfileManager(filepath.Join(t.TempDir(), "profiles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := []string{"source-0", "source-1", "source-2", "source-3"}
+	for _, source := range sources {
+		if _, err := pm.Create(source); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(pm.Path(source), "Cookies"), []byte(source), 0600)
+	}
+
+	errs := make(chan error, len(sources))
+	for _, source := range sources {
+		go func() {
+			_, err := pm.Clone(source, "target")
+			errs <- err
+		}()
+	}
+	succeeded := 0
+	for range sources {
+		if err := <-errs; err == nil {
+			succeeded++
+		} else if !errors.Is(err, ErrProfileExists) {
+			t.Errorf("expected ErrProfileExists for the other clones, got %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one clone to succeed, got %d", succeeded)
+	}
+	data, _ := os.ReadFile(filepath.Join(pm.Path("target"), "Cookies"))
+	if !strings.HasPrefix(string(data), "source-") {
+		t.Errorf("expected the files of one source, got %q", data)
+	}
+}
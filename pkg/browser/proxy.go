@@ -0,0 +1,80 @@
+package browser
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Picks the proxy of a new context from proxy_pool, e.g. to give each agent in a shared browser its own egress.
+// Returning nil leaves the context on the proxy of the browser.
+type ProxySelector func(bc *BrowserContext, pool []playwright.Proxy) (*playwright.Proxy, error)
+
+// Selector handing out the proxies of the pool in turn, the default
+func RoundRobinProxySelector() ProxySelector {
+	var mu sync.Mutex
+	next := 0
+	return func(bc *BrowserContext, pool []playwright.Proxy) (*playwright.Proxy, error) {
+		if len(pool) == 0 {
+			return nil, nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		proxy := pool[next%len(pool)]
+		next++
+		return &proxy, nil
+	}
+}
+
+// Set the selector picking the proxies of new contexts from proxy_pool, nil for round robin
+func (b *Browser) SetProxySelector(selector ProxySelector) {
+	if selector == nil {
+		selector = RoundRobinProxySelector()
+	}
+	b.proxySelector = selector
+}
+
+// Proxy of a new context: context_proxy, or one picked from proxy_pool. nil uses the proxy of the browser.
+// A connected browser was not launched with the proxy of the browser, its contexts use it instead.
+func (bc *BrowserContext) selectProxy() (*playwright.Proxy, error) {
+	if bc.Config.Proxy != nil {
+		return bc.Config.Proxy, nil
+	}
+	pool := bc.Browser.Config.ProxyPool
+	if len(pool) == 0 {
+		if bc.Browser.isConnected() {
+			return bc.Browser.Config.Proxy, nil
+		}
+		return nil, nil
+	}
+	proxy, err := bc.Browser.proxySelector(bc, pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select a proxy: %w", err)
+	}
+	return proxy, nil
+}
+
+// Server of the proxy the context was created with, empty if it uses the proxy of the browser
+func (bc *BrowserContext) ProxyServer() string {
+	if bc.proxy == nil {
+		return ""
+	}
+	return bc.proxy.Server
+}
+
+// Chrome args for the proxy of a browser launched from browser_binary_path
+func chromeProxyArgs(proxy *playwright.Proxy) []string {
+	if proxy == nil {
+		return nil
+	}
+	args := []string{"--proxy-server=" + proxy.Server}
+	if proxy.Bypass != nil && *proxy.Bypass != "" {
+		args = append(args, "--proxy-bypass-list="+*proxy.Bypass)
+	}
+	if proxy.Username != nil && *proxy.Username != "" {
+		log.Warn("⚠️ chrome does not take proxy credentials on the command line, use context_proxy or proxy_pool for a proxy with credentials")
+	}
+	return args
+}
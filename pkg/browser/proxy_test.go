@@ -0,0 +1,70 @@
+package browser
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestSelectProxy(t *testing.T) {
+	browser := NewBrowserWithSettings(NewBrowserSettings(WithProxyPool(
+		playwright.Proxy{Server: "http://proxy-a:8080"},
+		playwright.Proxy{Server: "http://proxy-b:8080"},
+	)))
+	servers := []string{}
+	for i := 0; i < 3; i++ {
+		proxy, err := browser.NewContext().selectProxy()
+		if err != nil {
+			t.Fatal(err)
+		}
+		servers = append(servers, proxy.Server)
+	}
+	if strings.Join(servers, " ") != "http://proxy-a:8080 http://proxy-b:8080 http://proxy-a:8080" {
+		t.Errorf("expected round robin over the pool, got %v", servers)
+	}
+
+	proxy, _ := browser.NewContext(WithContextProxy(playwright.Proxy{Server: "http://own:8080"})).selectProxy()
+	if proxy == nil || proxy.Server != "http://own:8080" {
+		t.Errorf("expected context_proxy to take precedence over the pool, got %v", proxy)
+	}
+
+	browser.SetProxySelector(func(bc *BrowserContext, pool []playwright.Proxy) (*playwright.Proxy, error) {
+		if bc.Config.Locale == "de-DE" {
+			return &pool[1], nil
+		}
+		return nil, errors.New("no proxy for this region")
+	})
+	if proxy, _ := browser.NewContext(WithLocale("de-DE")).selectProxy(); proxy == nil || proxy.Server != "http://proxy-b:8080" {
+		t.Errorf("expected the selector to pick proxy-b, got %v", proxy)
+	}
+	if _, err := browser.NewContext().selectProxy(); err == nil || !strings.Contains(err.Error(), "no proxy for this region") {
+		t.Errorf("expected the selector error, got %v", err)
+	}
+
+	if proxy, _ := NewBrowserWithSettings(nil).NewContext().selectProxy(); proxy != nil {
+		t.Errorf("expected no context proxy without pool, got %v", proxy)
+	}
+
+	launched := NewBrowserWithSettings(NewBrowserSettings(WithProxy(playwright.Proxy{Server: "http://default:8080"})))
+	if proxy, _ := launched.NewContext().selectProxy(); proxy != nil {
+		t.Errorf("expected a launched browser to apply its proxy itself, got %v", proxy)
+	}
+	connected := NewBrowserWithSettings(NewBrowserSettings(WithCdpUrl("http://localhost:9222"), WithProxy(playwright.Proxy{Server: "http://default:8080"})))
+	if proxy, _ := connected.NewContext().selectProxy(); proxy == nil || proxy.Server != "http://default:8080" {
+		t.Errorf("expected the contexts of a connected browser to use its proxy, got %v", proxy)
+	}
+}
+
+func TestChromeProxyArgs(t *testing.T) {
+	args := chromeProxyArgs(&playwright.Proxy{Server: "socks5://proxy:1080", Bypass: playwright.String("localhost,.internal")})
+	expected := []string{"--proxy-server=socks5://proxy:1080", "--proxy-bypass-list=localhost,.internal"}
+	if !slices.Equal(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+	if args := chromeProxyArgs(nil); len(args) != 0 {
+		t.Errorf("expected no args without proxy, got %v", args)
+	}
+}
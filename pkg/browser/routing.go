@@ -0,0 +1,340 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Supported values of RouteRule.Action
+const (
+	RouteActionBlock   = "block"   // abort the request
+	RouteActionMock    = "mock"    // respond with the content of a local file
+	RouteActionHeaders = "headers" // add or override request headers, later rules still apply
+)
+
+// Resource types a route rule can match, "tracker" matches requests to known analytics and ad urls
+var ROUTE_RESOURCE_TYPES = []string{
+	"document",
+	"stylesheet",
+	"image",
+	"media",
+	"font",
+	"script",
+	"texttrack",
+	"xhr",
+	"fetch",
+	"eventsource",
+	"websocket",
+	"manifest",
+	"other",
+	"tracker",
+}
+
+// Urls containing one of these patterns are matched by the "tracker" resource type
+var TRACKER_URL_PATTERNS = []string{
+	"analytics",
+	"tracking",
+	"telemetry",
+	"doubleclick",
+	"adsystem",
+	"adserver",
+	"advertising",
+	"hotjar",
+	"googletagmanager",
+}
+
+// A rule for the requests of all pages of a context.
+// Rules are checked in order, the first matching block or mock rule handles the request.
+type RouteRule struct {
+	Url           string            `json:"url,omitempty"`            // glob for the request url, * matches within a path segment, ** anything, {a,b} alternatives; all urls if empty
+	ResourceTypes []string          `json:"resource_types,omitempty"` // see ROUTE_RESOURCE_TYPES, all types if empty
+	Action        string            `json:"action"`                   // one of block, mock, headers
+	File          string            `json:"file,omitempty"`           // mock: file to respond with
+	Status        int               `json:"status,omitempty"`         // mock: status code, 200 if 0
+	ContentType   string            `json:"content_type,omitempty"`   // mock: inferred from the file extension if empty
+	Headers       map[string]string `json:"headers,omitempty"`        // headers: request headers to add or override
+}
+
+func (r *RouteRule) validate() error {
+	var errs []error
+	switch r.Action {
+	case RouteActionBlock:
+	case RouteActionMock:
+		if r.File == "" {
+			errs = append(errs, errors.New("mock requires a file"))
+		}
+		if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+			errs = append(errs, fmt.Errorf("status must be a http status code, got %d", r.Status))
+		}
+	case RouteActionHeaders:
+		if len(r.Headers) == 0 {
+			errs = append(errs, errors.New("headers requires headers"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("action must be one of %s, %s, %s, got %q", RouteActionBlock, RouteActionMock, RouteActionHeaders, r.Action))
+	}
+	for _, resourceType := range r.ResourceTypes {
+		if !slices.Contains(ROUTE_RESOURCE_TYPES, resourceType) {
+			errs = append(errs, fmt.Errorf("unknown resource type %q", resourceType))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Convert a url glob to a regular expression matching the whole url
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	inGroup := false
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '{' && !inGroup:
+			sb.WriteString("(?:")
+			inGroup = true
+		case c == '}' && inGroup:
+			sb.WriteString(")")
+			inGroup = false
+		case c == ',' && inGroup:
+			sb.WriteString("|")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if inGroup {
+		return nil, fmt.Errorf("unclosed { in url glob %q", glob)
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+type compiledRouteRule struct {
+	RouteRule
+	pattern *regexp.Regexp
+}
+
+func (r *compiledRouteRule) matches(request playwright.Request) bool {
+	if r.pattern != nil && !r.pattern.MatchString(request.URL()) {
+		return false
+	}
+	if len(r.ResourceTypes) == 0 || slices.Contains(r.ResourceTypes, request.ResourceType()) {
+		return true
+	}
+	if slices.Contains(r.ResourceTypes, "tracker") {
+		url := strings.ToLower(request.URL())
+		for _, pattern := range TRACKER_URL_PATTERNS {
+			if strings.Contains(url, pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compileRouteRules(rules []RouteRule) ([]*compiledRouteRule, error) {
+	compiled := make([]*compiledRouteRule, 0, len(rules))
+	var errs []error
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("route_rules[%d]: %w", i, err))
+			continue
+		}
+		if rule.Action == RouteActionMock {
+			if stat, err := os.Stat(rule.File); err != nil {
+				errs = append(errs, fmt.Errorf("route_rules[%d]: mock file %s: %w", i, rule.File, err))
+				continue
+			} else if stat.IsDir() {
+				errs = append(errs, fmt.Errorf("route_rules[%d]: mock file %s is a directory", i, rule.File))
+				continue
+			}
+		}
+		c := &compiledRouteRule{RouteRule: rule}
+		if rule.Url != "" {
+			pattern, err := globToRegexp(rule.Url)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("route_rules[%d]: %w", i, err))
+				continue
+			}
+			c.pattern = pattern
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, errors.Join(errs...)
+}
+
+// Read route rules from a JSON file with a list of rules.
+// Relative mock files are resolved against the directory of the rules file.
+func LoadRouteRules(path string) ([]RouteRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []RouteRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid route rules file %s: %w", path, err)
+	}
+	for i := range rules {
+		if rules[i].File != "" && !filepath.IsAbs(rules[i].File) {
+			rules[i].File = filepath.Join(filepath.Dir(path), rules[i].File)
+		}
+	}
+	return rules, nil
+}
+
+// Applies the route rules to the requests of all pages of a context.
+// Requests are only intercepted while there are rules, since interception disables the http cache.
+type router struct {
+	mu        sync.Mutex
+	rules     []*compiledRouteRule
+	context   playwright.BrowserContext
+	handler   func(playwright.Route)
+	installed bool
+}
+
+func newRouter() *router {
+	r := &router{}
+	r.handler = r.handle
+	return r
+}
+
+func (r *router) attach(context playwright.BrowserContext) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.context = context
+	r.installed = false
+	return r.sync()
+}
+
+func (r *router) detach() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.context = nil
+	r.installed = false
+}
+
+func (r *router) setRules(rules []*compiledRouteRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = rules
+	return r.sync()
+}
+
+// Route or unroute the context depending on the rules, must hold mu
+func (r *router) sync() error {
+	if r.context == nil {
+		return nil
+	}
+	if len(r.rules) > 0 && !r.installed {
+		if err := r.context.Route("**/*", r.handler); err != nil {
+			return fmt.Errorf("failed to install route rules: %w", err)
+		}
+		r.installed = true
+	} else if len(r.rules) == 0 && r.installed {
+		if err := r.context.Unroute("**/*", r.handler); err != nil {
+			return fmt.Errorf("failed to remove route rules: %w", err)
+		}
+		r.installed = false
+	}
+	return nil
+}
+
+func (r *router) handle(route playwright.Route) {
+	r.mu.Lock()
+	rules := r.rules
+	r.mu.Unlock()
+
+	request := route.Request()
+	var headers map[string]string
+	var err error
+	for _, rule := range rules {
+		if !rule.matches(request) {
+			continue
+		}
+		switch rule.Action {
+		case RouteActionBlock:
+			log.Debugf("🚫  Blocked %s", request.URL())
+			if err = route.Abort("blockedbyclient"); err != nil {
+				log.Debugf("🪨  Failed to block %s: %s", request.URL(), err)
+			}
+			return
+		case RouteActionMock:
+			log.Debugf("🎭  Mocked %s with %s", request.URL(), rule.File)
+			if err = route.Fulfill(mockResponse(&rule.RouteRule)); err != nil {
+				// an unhandled route stalls the request, so abort it like the mocked server failed
+				log.Warnf("⚠️ Failed to mock %s with %s: %s", request.URL(), rule.File, err)
+				if err = route.Abort("failed"); err != nil {
+					log.Debugf("🪨  Failed to abort %s: %s", request.URL(), err)
+				}
+			}
+			return
+		case RouteActionHeaders:
+			if headers == nil {
+				headers = request.Headers()
+			}
+			for name, value := range rule.Headers {
+				headers[strings.ToLower(name)] = value
+			}
+		}
+	}
+	// fall back instead of continuing, so other routes of the context still apply
+	if headers != nil {
+		err = route.Fallback(playwright.RouteFallbackOptions{Headers: headers})
+	} else {
+		err = route.Fallback()
+	}
+	if err != nil {
+		log.Debugf("🪨  Failed to continue %s: %s", request.URL(), err)
+	}
+}
+
+func mockResponse(rule *RouteRule) playwright.RouteFulfillOptions {
+	options := playwright.RouteFulfillOptions{Path: playwright.String(rule.File)}
+	if rule.Status != 0 {
+		options.Status = playwright.Int(rule.Status)
+	}
+	if rule.ContentType != "" {
+		options.ContentType = playwright.String(rule.ContentType)
+	}
+	return options
+}
+
+// Replace the route_rules of the context, applied to all pages immediately
+func (bc *BrowserContext) SetRouteRules(rules []RouteRule) error {
+	if _, err := compileRouteRules(rules); err != nil {
+		return err
+	}
+	bc.Config.RouteRules = rules
+	return bc.ReloadRouteRules()
+}
+
+// Apply the route_rules and the rules of route_rules_file again, e.g. after the file has changed
+func (bc *BrowserContext) ReloadRouteRules() error {
+	rules := slices.Clone(bc.Config.RouteRules)
+	if bc.Config.RouteRulesFile != "" {
+		fileRules, err := LoadRouteRules(bc.Config.RouteRulesFile)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, fileRules...)
+	}
+	compiled, err := compileRouteRules(rules)
+	if err != nil {
+		return err
+	}
+	return bc.router.setRules(compiled)
+}
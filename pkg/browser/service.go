@@ -1,14 +1,18 @@
 package browser
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -18,187 +22,405 @@ import (
 
 var IN_DOCKER = os.Getenv("IN_DOCKER") == "true"
 
-type BrowserConfig = map[string]interface{}
-
-func NewBrowserConfig() BrowserConfig {
-	return BrowserConfig{
-		"headless":         false,
-		"disable_security": false,
-		"browser_class":    "chromium",
-		"is_mobile":        false,
-		"has_touch":        false,
-	}
+type Browser struct {
+	Config              *BrowserSettings
+	Playwright          *playwright.Playwright
+	PlaywrightBrowser   playwright.Browser
+	chromeProcess       *os.Process
+	chromeExited        chan struct{}             // closed when chromeProcess has exited
+	tempUserDataDir     string                    // user data directory created for browser_binary_path, removed on close
+	persistentContext   playwright.BrowserContext // context of the builtin browser launched with a user data directory
+	persistentEmulation *contextEmulation
+	profileLock         *ProfileLock
+	proxySelector       ProxySelector // picks the proxies of new contexts from proxy_pool
+	configErr           error         // error of the map configuration given to NewBrowser
+	mu                  sync.Mutex    // guards Start and Close, a browser is shared by the contexts of several agents
 }
 
-type Browser struct {
-	Config            BrowserConfig
-	Playwright        *playwright.Playwright
-	PlaywrightBrowser playwright.Browser
-	chromeProcess     *os.Process
+// Create a browser from a map configuration, applied over the defaults with BrowserConfigFromMap.
+// An invalid configuration is logged and returned by Start.
+// The playwright browser is started lazily on first use.
+func NewBrowser(config BrowserConfig) *Browser {
+	settings, err := BrowserConfigFromMap(config)
+	if err != nil {
+		log.Errorf("❌  Invalid browser config: %s", err)
+		b := NewBrowserWithSettings(nil)
+		b.configErr = fmt.Errorf("invalid browser config: %w", err)
+		return b
+	}
+	return NewBrowserWithSettings(settings)
 }
 
-func NewBrowser(customConfig BrowserConfig) *Browser {
-	config := NewBrowserConfig()
-	for key, value := range customConfig {
-		config[key] = value
+// Create a browser with typed settings, nil for the defaults.
+// The playwright browser is started lazily on first use.
+func NewBrowserWithSettings(settings *BrowserSettings) *Browser {
+	if settings == nil {
+		settings = NewBrowserSettings()
 	}
 	return &Browser{
-		Config:            config,
+		Config:            settings,
 		Playwright:        nil,
 		PlaywrightBrowser: nil,
+		proxySelector:     RoundRobinProxySelector(),
 	}
 }
 
-func (b *Browser) NewContext() *BrowserContext {
+// Create a new browser context from the browser's NewContextConfig, adjusted by the given options
+func (b *Browser) NewContext(opts ...ContextOption) *BrowserContext {
+	config := b.Config.NewContextConfig
+	config.AllowedDomains = slices.Clone(config.AllowedDomains)
+	config.RouteRules = slices.Clone(config.RouteRules)
+	for _, opt := range opts {
+		opt(&config)
+	}
 	return &BrowserContext{
 		ContextId: uuid.New().String(),
-		Config:    b.Config,
+		Config:    &config,
 		Browser:   b,
 		Session:   nil,
 		State:     &BrowserContextState{},
+		dialogs:   &dialogRecorder{config: &config},
+		router:    newRouter(),
+		storage:   &storageStateSaver{},
 	}
 }
 
-// Get a browser context
+// Get the playwright browser, starting it if needed. Panics if the browser can not be started, use Start to handle errors.
 func (b *Browser) GetPlaywrightBrowser() playwright.Browser {
-	if b.PlaywrightBrowser == nil {
-		return b.init()
+	if err := b.Start(context.Background()); err != nil {
+		panic(err)
 	}
 	return b.PlaywrightBrowser
 }
 
+// Start the playwright driver and set up the browser. Starting a started browser does nothing.
+// Errors wrap ErrDriverNotInstalled, ErrBrowserNotInstalled, ErrCDPUnreachable or ErrBrowserLaunch where applicable.
+func (b *Browser) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.PlaywrightBrowser != nil || b.persistentContext != nil {
+		return nil
+	}
+	if b.configErr != nil {
+		return b.configErr
+	}
+	if err := b.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid browser config: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := b.lockProfile(); err != nil {
+		return err
+	}
+
+	pw, err := playwright.Run()
+	if err != nil {
+		// releases the profile lock
+		b.close()
+		return driverError(err)
+	}
+	b.Playwright = pw
+
+	browser, err := b.setupBrowser(ctx, pw)
+	if err != nil {
+		b.close()
+		return err
+	}
+	b.PlaywrightBrowser = browser
+	return nil
+}
+
+// Close the browser, stop the chrome process launched for browser_binary_path and the playwright driver.
+// The browser can be started again afterwards.
 func (b *Browser) Close(options ...playwright.BrowserCloseOptions) error {
-	if b.chromeProcess != nil {
-		// b.chromeProcess.Kill()
-		log.Debug("Check kill chrome process")
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.close(options...)
+}
+
+func (b *Browser) close(options ...playwright.BrowserCloseOptions) error {
+	var err error
+	if b.persistentContext != nil {
+		// closing the persistent context closes its browser
+		err = b.persistentContext.Close()
+		b.persistentContext = nil
+		b.persistentEmulation = nil
+		b.PlaywrightBrowser = nil
+	}
+	if b.PlaywrightBrowser != nil {
+		err = b.PlaywrightBrowser.Close(options...)
+		b.PlaywrightBrowser = nil
+	}
+	b.stopChromeProcess()
+	if b.tempUserDataDir != "" {
+		if err := os.RemoveAll(b.tempUserDataDir); err != nil {
+			log.Warnf("⚠️ Failed to remove temporary user data dir %s: %s", b.tempUserDataDir, err)
+		}
+		b.tempUserDataDir = ""
 	}
-	if b.PlaywrightBrowser == nil {
-		return nil
+	if b.Playwright != nil {
+		if err := b.Playwright.Stop(); err != nil {
+			log.Debugf("🪨  Failed to stop playwright: %s", err)
+		}
+		b.Playwright = nil
+	}
+	if b.profileLock != nil {
+		if err := b.profileLock.Unlock(); err != nil {
+			log.Warnf("⚠️ Failed to unlock profile %s: %s", b.Config.Profile, err)
+		}
+		b.profileLock = nil
 	}
-	return b.PlaywrightBrowser.Close(options...)
+	return err
 }
 
-func (b *Browser) init() playwright.Browser {
-	playwright, err := playwright.Run()
+// Lock the profile of the config, so no other browser uses it at the same time
+func (b *Browser) lockProfile() error {
+	if b.Config.Profile == "" {
+		return nil
+	}
+	profiles, err := NewProfileManager(b.Config.ProfilesDir)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	b.Playwright = playwright
+	if _, err := profiles.Ensure(b.Config.Profile); err != nil {
+		return err
+	}
+	lock, err := profiles.Lock(b.Config.Profile)
+	if err != nil {
+		return err
+	}
+	b.profileLock = lock
+	log.Debugf("🔒  Locked profile %s", b.Config.Profile)
+	return nil
+}
 
-	b.PlaywrightBrowser = b.setupBrowser(playwright)
-	return b.PlaywrightBrowser
+// The user data directory of the configured profile or user_data_dir
+func (b *Browser) userDataDir() string {
+	if b.Config.Profile != "" {
+		return (&ProfileManager{Root: b.Config.ProfilesDir}).Path(b.Config.Profile)
+	}
+	return b.Config.UserDataDir
 }
 
-func (b *Browser) setupBrowser(pw *playwright.Playwright) playwright.Browser {
-	if b.Config["cdp_url"] != nil {
+// Start the chrome process in its own process group and watch for its exit
+func (b *Browser) startChromeProcess(cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	b.chromeProcess = cmd.Process
+	b.chromeExited = make(chan struct{})
+	go func(exited chan struct{}) {
+		cmd.Wait()
+		close(exited)
+	}(b.chromeExited)
+	return nil
+}
+
+// Time chrome gets to exit after SIGTERM before its process group is killed
+const CHROME_SHUTDOWN_TIMEOUT = 5 * time.Second
+
+// Stop the launched chrome process gracefully, then kill whatever is left of its process group
+func (b *Browser) stopChromeProcess() {
+	if b.chromeProcess == nil {
+		return
+	}
+	pid := b.chromeProcess.Pid
+	log.Debugf("🛑 Stopping chrome process with PID: %d", pid)
+	if err := terminateProcessGroup(b.chromeProcess); err != nil {
+		log.Debugf("🪨  Failed to terminate chrome process %d: %s", pid, err)
+	}
+	select {
+	case <-b.chromeExited:
+	case <-time.After(CHROME_SHUTDOWN_TIMEOUT):
+		log.Warnf("⚠️ Chrome process %d did not exit within %s, killing it", pid, CHROME_SHUTDOWN_TIMEOUT)
+	}
+	// helper processes may outlive the main process
+	if err := killProcessGroup(b.chromeProcess); err != nil {
+		log.Debugf("🪨  Failed to kill chrome process group %d: %s", pid, err)
+	}
+	select {
+	case <-b.chromeExited:
+	case <-time.After(CHROME_SHUTDOWN_TIMEOUT):
+		log.Errorf("❌  Chrome process %d is still running", pid)
+	}
+	b.chromeProcess = nil
+	b.chromeExited = nil
+}
+
+// Whether the browser is connected with cdp_url or wss_url rather than launched
+func (b *Browser) isConnected() bool {
+	return b.Config.CdpUrl != "" || b.Config.WssUrl != ""
+}
+
+func (b *Browser) setupBrowser(ctx context.Context, pw *playwright.Playwright) (playwright.Browser, error) {
+	if b.Config.CdpUrl != "" {
 		return b.setupRemoteCdpBrowser(pw)
 	}
-	if b.Config["wss_url"] != nil {
+	if b.Config.WssUrl != "" {
 		return b.setupRemoteWssBrowser(pw)
 	}
 
-	if headless, ok := b.Config["headless"].(bool); ok && headless {
+	if b.Config.Headless {
 		log.Warn("⚠️ Headless mode is not recommended. Many sites will detect and block all headless browsers.")
 	}
 
-	if b.Config["browser_binary_path"] != nil {
-		return b.setupUserProvidedBrowser(pw)
+	if b.Config.BrowserBinaryPath != "" {
+		return b.setupUserProvidedBrowser(ctx, pw)
+	}
+	if b.userDataDir() != "" {
+		return b.setupPersistentBrowser(pw)
 	}
 	return b.setupBuiltinBrowser(pw)
 }
 
 // Sets up and returns a Playwright Browser instance with anti-detection measures. Firefox has no longer CDP support.
-func (b *Browser) setupRemoteCdpBrowser(pw *playwright.Playwright) playwright.Browser {
-	binaryPath, ok := b.Config["browser_binary_path"].(string)
-	if ok && strings.Contains(strings.ToLower(binaryPath), "firefox") {
-		panic("CDP has been deprecated for firefox, check: https://fxdx.dev/deprecating-cdp-support-in-firefox-embracing-the-future-with-webdriver-bidi/")
+func (b *Browser) setupRemoteCdpBrowser(pw *playwright.Playwright) (playwright.Browser, error) {
+	if strings.Contains(strings.ToLower(b.Config.BrowserBinaryPath), "firefox") {
+		return nil, errors.New("CDP has been deprecated for firefox, check: https://fxdx.dev/deprecating-cdp-support-in-firefox-embracing-the-future-with-webdriver-bidi/")
 	}
-	cdpUrl, ok := b.Config["cdp_url"].(string)
-	if !ok || len(cdpUrl) == 0 {
-		panic("CDP URL is required")
+	cdpUrl := b.Config.CdpUrl
+	if len(cdpUrl) == 0 {
+		return nil, errors.New("CDP URL is required")
 	}
 	log.Infof("🔌  Connecting to remote browser via CDP %s", cdpUrl)
 	browserClass := pw.Chromium
 	browser, err := browserClass.ConnectOverCDP(cdpUrl)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%w: %s: %w", ErrCDPUnreachable, cdpUrl, err)
 	}
-	return browser
+	return browser, nil
 }
 
 // Sets up and returns a Playwright Browser instance with anti-detection measures.
-func (b *Browser) setupRemoteWssBrowser(pw *playwright.Playwright) playwright.Browser {
-	wssUrl, ok := b.Config["wss_url"].(string)
-	if !ok || len(wssUrl) == 0 {
-		panic("WSS URL is required")
+func (b *Browser) setupRemoteWssBrowser(pw *playwright.Playwright) (playwright.Browser, error) {
+	wssUrl := b.Config.WssUrl
+	if len(wssUrl) == 0 {
+		return nil, errors.New("WSS URL is required")
 	}
 	log.Infof("🔌  Connecting to remote browser via WSS %s", wssUrl)
-	browserClass := pw.Chromium
-	browser, err := browserClass.Connect(wssUrl)
+	browser, err := b.browserType(pw).Connect(wssUrl)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to connect to %s: %w", wssUrl, err)
+	}
+	return browser, nil
+}
+
+// Name of the file chrome writes the remote debugging port to, inside the user data directory
+const DEVTOOLS_ACTIVE_PORT_FILE = "DevToolsActivePort"
+
+// Check if a local tcp port can be listened on
+func isPortFree(port int) bool {
+	ln, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// Read the remote debugging port chrome wrote to the DevToolsActivePort file, waiting until it exists
+func waitForDevToolsActivePort(ctx context.Context, path string, timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if content, err := os.ReadFile(path); err == nil {
+			line, _, _ := strings.Cut(string(content), "\n")
+			if port, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && port > 0 {
+				return port, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("%s was not written within %s", path, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Check if a browser answers on the given CDP endpoint
+func isCdpEndpointAlive(endpoint string) bool {
+	client := &http.Client{
+		Timeout: 2 * time.Second,
+	}
+	response, err := client.Get(endpoint + "/json/version")
+	if err != nil {
+		return false
 	}
-	return browser
+	response.Body.Close()
+	return response.StatusCode == 200
 }
 
-func getChromeUserDataDir() string {
-	tempDir := os.TempDir()
-	pid := os.Getpid()
-	return filepath.Join(tempDir, "chrome-profile-"+strconv.Itoa(pid))
+func connectUserProvidedBrowser(pw *playwright.Playwright, endpoint string) (playwright.Browser, error) {
+	browser, err := pw.Chromium.ConnectOverCDP(
+		endpoint,
+		playwright.BrowserTypeConnectOverCDPOptions{
+			Timeout: playwright.Float(20000),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrCDPUnreachable, endpoint, err)
+	}
+	return browser, nil
 }
 
 // Sets up and returns a Playwright Browser instance with anti-detection measures.
-func (b *Browser) setupUserProvidedBrowser(pw *playwright.Playwright) playwright.Browser {
-	binaryPath, ok := b.Config["browser_binary_path"].(string)
-	if !ok {
-		panic("A browser_binary_path is required")
+// The browser listens on remote_debugging_port, or on a port it picks itself, which is read from DevToolsActivePort.
+func (b *Browser) setupUserProvidedBrowser(ctx context.Context, pw *playwright.Playwright) (playwright.Browser, error) {
+	binaryPath := b.Config.BrowserBinaryPath
+	if binaryPath == "" {
+		return nil, errors.New("A browser_binary_path is required")
 	}
 
 	if _, err := os.Stat(binaryPath); os.IsNotExist(err) {
 		log.Errorf("Chrome binary not found: %s", binaryPath)
-		panic(err)
+		return nil, fmt.Errorf("%w: chrome binary not found: %s", ErrBrowserLaunch, binaryPath)
 	}
 
-	if b.Config["browser_class"] != "chromium" {
-		panic("browser_binary_path only supports chromium browsers (make sure browser_class=chromium)")
+	if b.Config.BrowserClass != BrowserClassChromium {
+		return nil, errors.New("browser_binary_path only supports chromium browsers (make sure browser_class=chromium)")
 	}
-	browserClass := pw.Chromium
 
-	// check if browser is already running
-	client := &http.Client{
-		Timeout: 2 * time.Second,
-	}
-	response, err := client.Get("http://localhost:9222/json/version")
-	if err != nil {
-		// Check if the error is a connection error
-		if _, ok := err.(net.Error); ok {
-			log.Debug("🌎  No existing Chrome instance found, starting a new one")
-		} else {
-			// Not a connection error, panic
-			panic(err)
+	port := b.Config.RemoteDebuggingPort
+	if port != 0 && !isPortFree(port) {
+		endpoint := "http://localhost:" + strconv.Itoa(port)
+		if !b.Config.AttachExisting {
+			return nil, fmt.Errorf("%w: remote debugging port %d is already in use, set attach_existing to reuse the browser listening there", ErrBrowserLaunch, port)
+		}
+		if !isCdpEndpointAlive(endpoint) {
+			return nil, fmt.Errorf("%w: %s is in use but does not answer as a browser", ErrCDPUnreachable, endpoint)
 		}
+		log.Infof("🔌  Reusing existing browser found running on %s", endpoint)
+		return connectUserProvidedBrowser(pw, endpoint)
 	}
-	if err == nil && response != nil && response.StatusCode == 200 {
-		log.Info("🔌  Reusing existing browser found running on http://localhost:9222")
 
-		browser, err := browserClass.ConnectOverCDP(
-			"http://localhost:9222",
-			playwright.BrowserTypeConnectOverCDPOptions{
-				Timeout: playwright.Float(20000),
-			},
-		)
+	// Start a new Chrome instance
+	userDataDir := b.userDataDir()
+	if userDataDir == "" {
+		dir, err := os.MkdirTemp("", "chrome-profile-")
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		return browser
+		userDataDir = dir
+		b.tempUserDataDir = dir
+	}
+	// a stale file from a previous run would point to the wrong port
+	activePortFile := filepath.Join(userDataDir, DEVTOOLS_ACTIVE_PORT_FILE)
+	if err := os.Remove(activePortFile); err != nil && !os.IsNotExist(err) {
+		return nil, err
 	}
 
-	// Start a new Chrome instance
 	argsMap := make(map[string]struct{})
 	var chromeArgs []string
 
 	addArgs := func(src []string) {
 		for _, arg := range src {
+			if strings.HasPrefix(arg, "--remote-debugging-port=") {
+				continue
+			}
 			if _, exists := argsMap[arg]; !exists {
 				argsMap[arg] = struct{}{}
 				chromeArgs = append(chromeArgs, arg)
@@ -206,94 +428,88 @@ func (b *Browser) setupUserProvidedBrowser(pw *playwright.Playwright) playwright
 		}
 	}
 
-	if v, ok := b.Config["user_data_dir"].(string); ok && v != "" {
-		chromeArgs = append(chromeArgs, "--user-data-dir="+v)
-	} else {
-		chromeArgs = append(chromeArgs, "--user-data-dir="+getChromeUserDataDir())
-	}
+	chromeArgs = append(chromeArgs,
+		"--user-data-dir="+userDataDir,
+		"--remote-debugging-port="+strconv.Itoa(port),
+	)
 
-	if v, ok := b.Config["profile_directory"].(string); ok && v != "" {
-		chromeArgs = append(chromeArgs, "--profile-directory="+v)
+	if b.Config.ProfileDirectory != "" {
+		chromeArgs = append(chromeArgs, "--profile-directory="+b.Config.ProfileDirectory)
 	}
 
 	addArgs(CHROME_ARGS)
 	if IN_DOCKER {
 		addArgs(CHROME_DOCKER_ARGS)
 	}
-	if v, ok := b.Config["headless"].(bool); ok && v {
+	if b.Config.Headless {
 		addArgs(CHROME_HEADLESS_ARGS)
 	}
-	if v, ok := b.Config["disable_security"].(bool); ok && v {
+	if b.Config.DisableSecurity {
 		addArgs(CHROME_DISABLE_SECURITY_ARGS)
 	}
-	if v, ok := b.Config["deterministic_rendering"].(bool); ok && v {
+	if b.Config.DeterministicRendering {
 		addArgs(CHROME_DETERMINISTIC_RENDERING_ARGS)
 	}
-	if extraArgs, ok := b.Config["extra_browser_args"].([]string); ok {
-		addArgs(extraArgs)
-	}
+	addArgs(chromeProxyArgs(b.Config.Proxy))
+	addArgs(b.Config.ExtraBrowserArgs)
 
 	chromeLaunchCmd := append([]string{binaryPath}, chromeArgs...)
 	log.Debugf("🚀 Launching Chrome with args: %v", chromeLaunchCmd)
 
 	cmd := exec.Command(chromeLaunchCmd[0], chromeLaunchCmd[1:]...)
 
-	logFile, err := os.Create("/tmp/chrome_launch.log")
-	if err != nil {
-		log.Errorf("Failed to create chrome log file: %v", err)
-		panic(err)
+	if b.Config.ChromeLogPath != "" {
+		logFile, err := os.OpenFile(b.Config.ChromeLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Errorf("Failed to open chrome log file: %v", err)
+			return nil, err
+		}
+		defer logFile.Close()
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
 	}
-	defer logFile.Close()
-	cmd.Stdout = logFile
-	cmd.Stderr = logFile
 
-	if err := cmd.Start(); err != nil {
-		panic(err)
+	if err := b.startChromeProcess(cmd); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBrowserLaunch, err)
 	}
-	// Optionally, store the process if you need to kill or inspect it later
-	b.chromeProcess = cmd.Process
 	log.Debugf("🚀 Chrome process started with PID: %d", b.chromeProcess.Pid)
 
-	// Attempt to connect again after starting a new instance
-	for i := 0; i < 10; i++ {
-		response, err := client.Get("http://localhost:9222/json/version")
-		if err != nil {
-			// Check if the error is a connection error
-			if _, ok := err.(net.Error); ok {
-				// It's a connection error, retry
-			} else {
-				// Not a connection error, panic
-				panic(err)
-			}
-		}
-		if response != nil && response.StatusCode == 200 {
-			break
-		}
-		time.Sleep(1 * time.Second)
-	}
-
-	// Attempt to connect again after starting a new instance
-	browser, err := browserClass.ConnectOverCDP(
-		"http://localhost:9222",
-		playwright.BrowserTypeConnectOverCDPOptions{
-			Timeout: playwright.Float(20000),
-		},
-	)
+	activePort, err := waitForDevToolsActivePort(ctx, activePortFile, 10*time.Second)
 	if err != nil {
 		log.Errorf("❌  Failed to start a new Chrome instance: %s", err)
-		panic(err)
+		return nil, fmt.Errorf("%w: %w (is another browser using %s?)", ErrCDPUnreachable, err, userDataDir)
 	}
-	return browser
+	endpoint := "http://localhost:" + strconv.Itoa(activePort)
+	log.Debugf("🔌  Chrome is listening on %s", endpoint)
+	return connectUserProvidedBrowser(pw, endpoint)
 }
 
-// Sets up and returns a Playwright Browser instance with anti-detection measures.
-func (b *Browser) setupBuiltinBrowser(pw *playwright.Playwright) playwright.Browser {
-	if b.Config["browser_binary_path"] != nil {
-		panic("browser_binary_path should be None if trying to use the builtin browsers")
+// Default args for the builtin firefox and webkit browsers, chrome args do not apply to them
+var FIREFOX_ARGS = []string{"-no-remote"}
+var WEBKIT_ARGS = []string{"--no-startup-window"}
+
+// Playwright browser type for the configured browser class
+func (b *Browser) browserType(pw *playwright.Playwright) playwright.BrowserType {
+	switch b.Config.BrowserClass {
+	case BrowserClassFirefox:
+		return pw.Firefox
+	case BrowserClassWebkit:
+		return pw.WebKit
+	default:
+		return pw.Chromium
 	}
+}
+
+// Whether the browser speaks CDP. Only chromium does, CDP based features are skipped for the other engines.
+func (b *Browser) supportsCdp() bool {
+	return b.Config.BrowserClass == BrowserClassChromium
+}
+
+// Args for the builtin chromium browser
+func (b *Browser) chromiumLaunchArgs() []string {
 	var screenSize map[string]int
 	var offsetX, offsetY int
-	if headless, ok := b.Config["headless"].(bool); ok && headless {
+	if b.Config.Headless {
 		screenSize = map[string]int{"width": 1920, "height": 1080}
 		offsetX, offsetY = 0, 0
 	} else {
@@ -307,13 +523,13 @@ func (b *Browser) setupBuiltinBrowser(pw *playwright.Playwright) playwright.Brow
 	if IN_DOCKER {
 		chromeArgs = append(chromeArgs, CHROME_DOCKER_ARGS...)
 	}
-	if b.Config["headless"] != nil && b.Config["headless"].(bool) {
+	if b.Config.Headless {
 		chromeArgs = append(chromeArgs, CHROME_HEADLESS_ARGS...)
 	}
-	if b.Config["disable_security"] != nil && b.Config["disable_security"].(bool) {
+	if b.Config.DisableSecurity {
 		chromeArgs = append(chromeArgs, CHROME_DISABLE_SECURITY_ARGS...)
 	}
-	if b.Config["deterministic_rendering"] != nil && b.Config["deterministic_rendering"].(bool) {
+	if b.Config.DeterministicRendering {
 		chromeArgs = append(chromeArgs, CHROME_DETERMINISTIC_RENDERING_ARGS...)
 	}
 
@@ -323,11 +539,6 @@ func (b *Browser) setupBuiltinBrowser(pw *playwright.Playwright) playwright.Brow
 		fmt.Sprintf("--window-size=%d,%d", screenSize["width"], screenSize["height"]),
 	)
 
-	// additional user specified args
-	if extraArgs, ok := b.Config["extra_browser_args"].([]string); ok {
-		chromeArgs = append(chromeArgs, extraArgs...)
-	}
-
 	// check if port 9222 is already taken, if so remove the remote-debugging-port arg to prevent conflicts
 	ln, err := net.Listen("tcp", "127.0.0.1:9222")
 	if err != nil {
@@ -340,67 +551,123 @@ func (b *Browser) setupBuiltinBrowser(pw *playwright.Playwright) playwright.Brow
 	} else {
 		ln.Close()
 	}
+	return chromeArgs
+}
 
-	browserType := pw.Chromium
-	// TODO(LOW): support firefox and webkit
-	// switch self.Config["browser_class"] {
-	// case "chromium":
-	// 	browserType = playwright.Chromium
-	// case "firefox":
-	// 	browserType = playwright.Firefox
-	// case "webkit":
-	// 	browserType = playwright.WebKit
-	// default:
-	// 	browserType = playwright.Chromium
-	// }
-	// args := map[string]interface{}{
-	// 	"chromium": chromeArgs,
-	// 	"firefox": []interface{}{
-	// 		"-no-remote",
-	// 		self.Config["extra_browser_args"],
-	// 	},
-	// 	"webkit": []interface{}{
-	// 		"--no-startup-window",
-	// 		self.Config["extra_browser_args"],
-	// 	},
-	// }
-	var proxySetting *playwright.Proxy = nil
-	if proxy, ok := b.Config["proxy"].(map[string]interface{}); ok {
-		server := proxy["server"].(string)
-		var bypassPtr *string = nil
-		bypass, ok := proxy["bypass"].(string)
-		if ok {
-			bypassPtr = &bypass
-		}
-		var usernamePtr *string = nil
-		username, ok := proxy["username"].(string)
-		if ok {
-			usernamePtr = &username
-		}
-		var passwordPtr *string = nil
-		password, ok := proxy["password"].(string)
-		if ok {
-			passwordPtr = &password
+// Args for the builtin browser of the configured browser class, persistent for a launch with a persistent context
+func (b *Browser) builtinLaunchArgs(persistent bool) []string {
+	var args []string
+	switch b.Config.BrowserClass {
+	case BrowserClassFirefox:
+		args = append(args, FIREFOX_ARGS...)
+	case BrowserClassWebkit:
+		// a persistent context is the startup window of webkit, it must not be suppressed
+		if !persistent {
+			args = append(args, WEBKIT_ARGS...)
 		}
+	default:
+		args = b.chromiumLaunchArgs()
+	}
+	if b.Config.BrowserClass != BrowserClassChromium && (b.Config.DisableSecurity || b.Config.DeterministicRendering) {
+		log.Debugf("disable_security and deterministic_rendering browser args are chromium only, skipped for %s", b.Config.BrowserClass)
+	}
 
-		proxySetting = &playwright.Proxy{
-			Server:   server,
-			Bypass:   bypassPtr,
-			Username: usernamePtr,
-			Password: passwordPtr,
-		}
+	// additional user specified args
+	return append(args, b.Config.ExtraBrowserArgs...)
+}
+
+func driverError(err error) error {
+	if strings.Contains(err.Error(), "please install the driver") {
+		return fmt.Errorf("%w: %w", ErrDriverNotInstalled, err)
+	}
+	return fmt.Errorf("%w: could not start the playwright driver: %w", ErrBrowserLaunch, err)
+}
+
+func (b *Browser) launchError(err error) error {
+	if strings.Contains(err.Error(), "Executable doesn't exist") {
+		return fmt.Errorf("%w: %s: %w", ErrBrowserNotInstalled, b.Config.BrowserClass, err)
+	}
+	return fmt.Errorf("%w: %w", ErrBrowserLaunch, err)
+}
+
+// Sets up and returns a Playwright Browser instance with anti-detection measures.
+func (b *Browser) setupBuiltinBrowser(pw *playwright.Playwright) (playwright.Browser, error) {
+	if b.Config.BrowserBinaryPath != "" {
+		return nil, errors.New("browser_binary_path should be None if trying to use the builtin browsers")
 	}
-	browser, err := browserType.Launch(
+
+	args := b.builtinLaunchArgs(false)
+	browser, err := b.browserType(pw).Launch(
 		playwright.BrowserTypeLaunchOptions{
-			Headless:      playwright.Bool(b.Config["headless"].(bool)),
-			Args:          chromeArgs,
-			Proxy:         proxySetting,
+			Headless:      playwright.Bool(b.Config.Headless),
+			Args:          args,
+			Proxy:         b.Config.Proxy,
 			HandleSIGTERM: playwright.Bool(false),
 			HandleSIGINT:  playwright.Bool(false),
 		},
 	)
 	if err != nil {
-		panic(err)
+		return nil, b.launchError(err)
+	}
+	return browser, nil
+}
+
+// Launches the builtin browser with a persistent context on the user data directory.
+// Contexts of the browser use this context, it is created with the options of NewContextConfig.
+func (b *Browser) setupPersistentBrowser(pw *playwright.Playwright) (playwright.Browser, error) {
+	args := b.builtinLaunchArgs(true)
+	if b.Config.ProfileDirectory != "" && b.Config.BrowserClass == BrowserClassChromium {
+		args = append(args, "--profile-directory="+b.Config.ProfileDirectory)
+	}
+	contextConfig := b.Config.NewContextConfig
+	emulation, err := newContextEmulation(pw.Devices, &contextConfig, b.Config.BrowserClass)
+	if err != nil {
+		return nil, err
+	}
+	proxy := b.Config.Proxy
+	if contextConfig.Proxy != nil {
+		proxy = contextConfig.Proxy
+	}
+	if len(b.Config.ProxyPool) > 0 {
+		log.Warn("⚠️ proxy_pool is ignored for the persistent context of a user data directory")
+	}
+	userDataDir := b.userDataDir()
+	context, err := b.browserType(pw).LaunchPersistentContext(userDataDir,
+		playwright.BrowserTypeLaunchPersistentContextOptions{
+			Headless:          playwright.Bool(b.Config.Headless),
+			Args:              args,
+			Proxy:             proxy,
+			HandleSIGTERM:     playwright.Bool(false),
+			HandleSIGINT:      playwright.Bool(false),
+			NoViewport:        emulation.noViewport,
+			Viewport:          emulation.viewport,
+			Screen:            emulation.screen,
+			UserAgent:         emulation.userAgent,
+			JavaScriptEnabled: playwright.Bool(true),
+			BypassCSP:         playwright.Bool(b.Config.DisableSecurity),
+			IgnoreHttpsErrors: playwright.Bool(b.Config.DisableSecurity),
+			Locale:            optionalString(contextConfig.Locale),
+			HttpCredentials:   contextConfig.HttpCredentials,
+			IsMobile:          emulation.isMobile,
+			HasTouch:          emulation.hasTouch,
+			TimezoneId:        optionalString(contextConfig.TimezoneId),
+			Geolocation:       contextConfig.Geolocation,
+			Permissions:       contextConfig.Permissions,
+			ColorScheme:       contextConfig.colorScheme(),
+			ReducedMotion:     contextConfig.reducedMotion(),
+			ExtraHttpHeaders:  contextConfig.ExtraHttpHeaders,
+			Offline:           playwright.Bool(contextConfig.Offline),
+			DeviceScaleFactor: emulation.deviceScaleFactor,
+		},
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "ProcessSingleton") || strings.Contains(err.Error(), "SingletonLock") {
+			return nil, fmt.Errorf("%w: %s is used by another browser: %w", ErrBrowserLaunch, userDataDir, err)
+		}
+		return nil, b.launchError(err)
 	}
-	return browser
+	log.Debugf("📁  Launched persistent context on %s", userDataDir)
+	b.persistentContext = context
+	b.persistentEmulation = emulation
+	return context.Browser(), nil
 }
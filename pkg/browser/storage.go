@@ -0,0 +1,200 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/nerdface-ai/browser-use-go/internals/utils"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Content of storage_state_file. Cookies and origins are playwright's storage state,
+// so the file can also be used with other playwright tools. IndexedDB is not included.
+type StorageState struct {
+	Cookies        []playwright.OptionalCookie `json:"cookies"`
+	Origins        []playwright.Origin         `json:"origins"`         // localStorage per origin
+	SessionStorage []SessionStorageOrigin      `json:"session_storage"` // sessionStorage of the open tabs per origin
+}
+
+type SessionStorageOrigin struct {
+	Origin string                 `json:"origin"`
+	Items  []playwright.NameValue `json:"items"`
+}
+
+// Read storage_state_file, nil if the file does not exist yet
+func (bc *BrowserContext) loadStorageState() (*StorageState, error) {
+	if bc.Config.StorageStateFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(bc.Config.StorageStateFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state StorageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid storage_state_file %s: %w", bc.Config.StorageStateFile, err)
+	}
+	log.Infof("🍪  Loaded storage state with %d cookies and %d origins from %s", len(state.Cookies), len(state.Origins), bc.Config.StorageStateFile)
+	return &state, nil
+}
+
+// Restore storage the context was not created with. Storage is only set where the page has no value yet.
+func restoreStorageState(context playwright.BrowserContext, state *StorageState, includeCookies bool, includeLocalStorage bool) error {
+	if includeCookies && len(state.Cookies) > 0 {
+		if err := context.AddCookies(state.Cookies); err != nil {
+			return err
+		}
+	}
+	storage := map[string]map[string][]playwright.NameValue{"sessionStorage": {}, "localStorage": {}}
+	for _, origin := range state.SessionStorage {
+		storage["sessionStorage"][origin.Origin] = origin.Items
+	}
+	if includeLocalStorage {
+		for _, origin := range state.Origins {
+			storage["localStorage"][origin.Origin] = origin.LocalStorage
+		}
+	}
+	if len(storage["sessionStorage"]) == 0 && len(storage["localStorage"]) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(storage)
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf(`(state => {
+		for (const [storageName, origins] of Object.entries(state)) {
+			const items = origins[location.origin];
+			if (!items) continue;
+			try {
+				const storage = window[storageName];
+				for (const { name, value } of items) {
+					if (storage.getItem(name) === null) storage.setItem(name, value);
+				}
+			} catch (e) {}
+		}
+	})(%s)`, data)
+	return context.AddInitScript(playwright.Script{Content: &script})
+}
+
+// Current cookies, localStorage and sessionStorage of the context
+func (bc *BrowserContext) captureStorageState(context playwright.BrowserContext) (*StorageState, error) {
+	playwrightState, err := context.StorageState()
+	if err != nil {
+		return nil, err
+	}
+	// the cookie fields are the same, only optional when loading
+	data, err := json.Marshal(playwrightState)
+	if err != nil {
+		return nil, err
+	}
+	state := &StorageState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	state.SessionStorage = []SessionStorageOrigin{}
+	seen := map[string]bool{}
+	for _, page := range context.Pages() {
+		result, err := page.Evaluate(`() => ({ origin: location.origin, items: Object.entries(sessionStorage).map(([name, value]) => ({ name, value })) })`)
+		if err != nil {
+			continue // closed or opaque origin pages have no sessionStorage
+		}
+		var origin SessionStorageOrigin
+		data, err := json.Marshal(result)
+		if err != nil || json.Unmarshal(data, &origin) != nil {
+			continue
+		}
+		if origin.Origin == "null" || len(origin.Items) == 0 || seen[origin.Origin] {
+			continue
+		}
+		seen[origin.Origin] = true
+		state.SessionStorage = append(state.SessionStorage, origin)
+	}
+	return state, nil
+}
+
+// Save the cookies, localStorage and sessionStorage of the context to storage_state_file
+func (bc *BrowserContext) SaveStorageState() error {
+	if bc.Config.StorageStateFile == "" || bc.Session == nil || bc.Session.Context == nil {
+		return nil
+	}
+	return bc.storage.save(bc, bc.Session.Context)
+}
+
+// Saves the storage state of a session, periodically and after page loads if configured
+type storageStateSaver struct {
+	mu     sync.Mutex
+	stop   chan struct{}
+	closed bool // the session is closing, the context must not be used anymore
+}
+
+func (ss *storageStateSaver) save(bc *BrowserContext, context playwright.BrowserContext) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.closed {
+		return nil
+	}
+	state, err := bc.captureStorageState(context)
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(state, "", "  "); err == nil {
+			err = utils.WriteFileAtomic(bc.Config.StorageStateFile, data, 0600)
+		}
+	}
+	if err != nil {
+		log.Warnf("❌  Failed to save storage state: %s", err)
+		return err
+	}
+	log.Debugf("🍪  Saved storage state with %d cookies to %s", len(state.Cookies), bc.Config.StorageStateFile)
+	return nil
+}
+
+func (ss *storageStateSaver) attach(bc *BrowserContext, context playwright.BrowserContext) {
+	ss.mu.Lock()
+	ss.closed = false
+	ss.mu.Unlock()
+	if bc.Config.StorageStateSaveInterval > 0 {
+		ss.stop = make(chan struct{})
+		go func(stop chan struct{}) {
+			ticker := time.NewTicker(seconds(bc.Config.StorageStateSaveInterval))
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					ss.save(bc, context)
+				}
+			}
+		}(ss.stop)
+	}
+	if bc.Config.StorageStateSaveOnLoad {
+		onPage := func(page playwright.Page) {
+			page.OnLoad(func(playwright.Page) {
+				// saving evaluates scripts in the pages, which must not happen in the event handler
+				go ss.save(bc, context)
+			})
+		}
+		for _, page := range context.Pages() {
+			onPage(page)
+		}
+		context.OnPage(onPage)
+	}
+}
+
+func (ss *storageStateSaver) detach() {
+	ss.mu.Lock()
+	ss.closed = true
+	ss.mu.Unlock()
+	if ss.stop != nil {
+		close(ss.stop)
+		ss.stop = nil
+	}
+}
@@ -0,0 +1,44 @@
+package browser
+
+import (
+	"time"
+)
+
+// Page load time that counts as normal speed for adaptive waits
+const ADAPTIVE_REFERENCE_LOAD_TIME = 1 * time.Second
+
+// Upper bound for scaling waits and timeouts on slow pages
+const ADAPTIVE_MAX_FACTOR = 4.0
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Record how long the network of a page took to become idle, as moving average
+func (bc *BrowserContext) recordPageLoad(d time.Duration) {
+	if bc.pageLoadAverage == 0 {
+		bc.pageLoadAverage = d
+		return
+	}
+	bc.pageLoadAverage = (bc.pageLoadAverage*3 + d) / 4
+}
+
+// Factor to scale waits and timeouts with, above 1 for slow pages if adaptive_waits is enabled
+func (bc *BrowserContext) timingFactor() float64 {
+	if !bc.Config.AdaptiveWaits {
+		return 1
+	}
+	factor := float64(bc.pageLoadAverage) / float64(ADAPTIVE_REFERENCE_LOAD_TIME)
+	return min(max(factor, 1), ADAPTIVE_MAX_FACTOR)
+}
+
+// Time to wait between the actions of a step
+func (bc *BrowserContext) WaitBetweenActions() time.Duration {
+	return time.Duration(float64(seconds(bc.Config.WaitBetweenActions)) * bc.timingFactor())
+}
+
+// Playwright timeout in milliseconds for a timeout configured in seconds
+func (bc *BrowserContext) timeoutMs(s float64) *float64 {
+	ms := s * 1000 * bc.timingFactor()
+	return &ms
+}
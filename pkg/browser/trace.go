@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Playwright trace of a session, saved to trace_path
+type tracer struct {
+	tracing playwright.Tracing
+	chunk   string // name of the recording chunk if trace_chunk_per_step is on
+}
+
+// Start tracing the context if trace_path is set
+func (bc *BrowserContext) startTracing(context playwright.BrowserContext) error {
+	if bc.Config.TracePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(bc.Config.TracePath, 0755); err != nil {
+		return fmt.Errorf("failed to create trace_path: %w", err)
+	}
+	tracing := context.Tracing()
+	err := tracing.Start(playwright.TracingStartOptions{
+		Name:        playwright.String(bc.ContextId),
+		Screenshots: playwright.Bool(true),
+		Snapshots:   playwright.Bool(true),
+		Sources:     playwright.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start tracing: %w", err)
+	}
+	bc.tracer = &tracer{tracing: tracing}
+	if bc.Config.TraceChunkPerStep {
+		// tracing starts with a chunk, it covers everything before the first step
+		bc.tracer.chunk = "start"
+	}
+	log.Debugf("🔍  Tracing to %s", bc.Config.TracePath)
+	return nil
+}
+
+// Save the current trace chunk and start a new one named name, e.g. per agent step.
+// Does nothing unless trace_path and trace_chunk_per_step are set.
+func (bc *BrowserContext) NextTraceChunk(name string) error {
+	if bc.tracer == nil || !bc.Config.TraceChunkPerStep {
+		return nil
+	}
+	if err := bc.saveTraceChunk(); err != nil {
+		return err
+	}
+	if err := bc.tracer.tracing.StartChunk(playwright.TracingStartChunkOptions{Name: playwright.String(name)}); err != nil {
+		return fmt.Errorf("failed to start trace chunk %s: %w", name, err)
+	}
+	bc.tracer.chunk = name
+	return nil
+}
+
+func (bc *BrowserContext) saveTraceChunk() error {
+	if bc.tracer.chunk == "" {
+		return nil
+	}
+	path := bc.newTracePath(bc.tracer.chunk + ".zip")
+	bc.tracer.chunk = ""
+	if err := bc.tracer.tracing.StopChunk(path); err != nil {
+		return fmt.Errorf("failed to save trace chunk %s: %w", path, err)
+	}
+	bc.tracePaths = append(bc.tracePaths, path)
+	return nil
+}
+
+func (bc *BrowserContext) newTracePath(filename string) string {
+	return filepath.Join(bc.Config.TracePath, uniqueFilename(bc.Config.TracePath, filename, nil))
+}
+
+// Save the trace of the session, called before the context is closed
+func (bc *BrowserContext) stopTracing() {
+	if bc.tracer == nil {
+		return
+	}
+	var err error
+	if bc.Config.TraceChunkPerStep {
+		if err = bc.saveTraceChunk(); err == nil {
+			err = bc.tracer.tracing.Stop()
+		}
+	} else {
+		path := bc.newTracePath("trace.zip")
+		if err = bc.tracer.tracing.Stop(path); err == nil {
+			bc.tracePaths = append(bc.tracePaths, path)
+		}
+	}
+	if err != nil {
+		log.Warnf("❌  Failed to save trace: %s", err)
+	}
+	bc.tracer = nil
+}
+
+// Paths of the traces saved by the sessions of this context, complete once the context is closed
+func (bc *BrowserContext) TracePaths() []string {
+	return bc.tracePaths
+}
@@ -58,3 +58,11 @@ func ParseNumberToFloat(value any) (float64, error) {
 	}
 	return 0, errors.New("value is not a number")
 }
+
+// Pointer to s, nil if s is empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,74 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Keeps the pages of a context in the order they were opened, to name their videos by tab
+type videoRecorder struct {
+	mu    sync.Mutex
+	name  string // prefix of the video files, fixed when the session starts
+	pages []playwright.Page
+}
+
+func (vr *videoRecorder) attach(context playwright.BrowserContext) {
+	for _, page := range context.Pages() {
+		vr.onPage(page)
+	}
+	context.OnPage(vr.onPage)
+}
+
+func (vr *videoRecorder) onPage(page playwright.Page) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	vr.pages = append(vr.pages, page)
+}
+
+// Move the videos of all pages to <name>_tab<n>.webm in dir, once the context is closed
+func (vr *videoRecorder) save(dir string) []string {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	name := vr.name
+	paths := []string{}
+	for tab, page := range vr.pages {
+		video := page.Video()
+		if video == nil {
+			continue
+		}
+		recorded, err := video.Path()
+		if err == nil {
+			target := filepath.Join(dir, uniqueFilename(dir, fmt.Sprintf("%s_tab%d.webm", name, tab), nil))
+			if err = os.Rename(recorded, target); err == nil {
+				paths = append(paths, target)
+				continue
+			}
+		}
+		log.Warnf("❌  Failed to save the video of tab %d: %s", tab, err)
+	}
+	return paths
+}
+
+// Name the videos of the sessions started after this call by name, e.g. the id of the agent using the context,
+// instead of the context id. The running session keeps its name, so agents sharing a session do not rename it.
+func (bc *BrowserContext) SetVideoName(name string) {
+	bc.videoName = name
+}
+
+// Paths of the videos recorded by the sessions of this context, complete once the context is closed
+func (bc *BrowserContext) VideoPaths() []string {
+	return bc.videoPaths
+}
+
+func (bc *BrowserContext) saveVideos() {
+	if bc.videos == nil {
+		return
+	}
+	bc.videoPaths = append(bc.videoPaths, bc.videos.save(bc.Config.RecordVideoDir)...)
+	bc.videos = nil
+}
@@ -1,6 +1,7 @@
 package browser
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -29,6 +30,22 @@ func TabsToString(tabs []*TabInfo) string {
 	return strings.Join(tabStrings, ", ")
 }
 
+// Size of the page area the agent sees
+type ViewportInfo struct {
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	DeviceScaleFactor float64 `json:"device_scale_factor"`
+	IsMobile          bool    `json:"is_mobile"`
+}
+
+func (vi *ViewportInfo) String() string {
+	layout := "desktop"
+	if vi.IsMobile {
+		layout = "mobile"
+	}
+	return fmt.Sprintf("%dx%d (%s, device scale factor %g)", vi.Width, vi.Height, layout, vi.DeviceScaleFactor)
+}
+
 type GroupTabsAction struct {
 	TabIds []int   `json:"tab_ids"`
 	Title  string  `json:"title"`
@@ -46,7 +63,11 @@ type BrowserState struct {
 	Screenshot    *string             `json:"screenshot,omitempty"`
 	PixelAbove    int                 `json:"pixel_above"`
 	PixelBelow    int                 `json:"pixel_below"`
-	BrowserErrors []string            `json:"browser_errors"`
+	Viewport      *ViewportInfo       `json:"viewport,omitempty"`
+	BrowserErrors []string            `json:"browser_errors"` // digest of BrowserLogs
+	BrowserLogs   []*BrowserLogEntry  `json:"browser_logs"`   // errors and warnings of the pages since the previous state
+	Downloads     []*DownloadInfo     `json:"downloads"`
+	Dialogs       []*DialogInfo       `json:"dialogs"` // dialogs handled since the previous state
 	ElementTree   *dom.DOMElementNode `json:"element_tree"`
 	SelectorMap   *dom.SelectorMap    `json:"selector_map"`
 }
@@ -56,6 +77,7 @@ type BrowserStateHistory struct {
 	Title             string                   `json:"title"`
 	Tabs              []*TabInfo               `json:"tabs"`
 	InteractedElement []*dom.DOMHistoryElement `json:"interacted_element"`
+	BrowserLogs       []*BrowserLogEntry       `json:"browser_logs"`
 }
 
 // BrowserError is the base error type for all browser errors.
@@ -79,3 +101,17 @@ func NewURLNotAllowedError(url string) error {
 		},
 	}
 }
+
+// Errors of Browser.Start and BrowserContext.Start, wrapped with details. Check them with errors.Is.
+var (
+	// The playwright driver is missing or outdated, install it with `go run github.com/playwright-community/playwright-go/cmd/playwright install`
+	ErrDriverNotInstalled = errors.New("playwright driver is not installed")
+	// The playwright browser for the configured browser_class is not installed
+	ErrBrowserNotInstalled = errors.New("playwright browser is not installed")
+	// The CDP endpoint of the browser could not be reached
+	ErrCDPUnreachable = errors.New("CDP endpoint is unreachable")
+	// The browser process could not be launched
+	ErrBrowserLaunch = errors.New("failed to launch browser")
+	// The profile is used by another browser, see ProfileManager.Lock
+	ErrProfileLocked = errors.New("profile is locked")
+)